@@ -34,12 +34,27 @@ type service struct {
 	diffapi.UnimplementedDiffServer
 }
 
+var _ diffapi.DiffServer = (*service)(nil)
+
 func FromApplierAndComparer(a diff.Applier, c diff.Comparer) diffapi.DiffServer {
 	return &service{
 		applier:  a,
 		comparer: c,
 	}
 }
+
+// FromApplier returns a diff server which only supports Apply; Diff returns
+// ErrNotImplemented.
+func FromApplier(a diff.Applier) diffapi.DiffServer {
+	return FromApplierAndComparer(a, nil)
+}
+
+// FromComparer returns a diff server which only supports Diff; Apply returns
+// ErrNotImplemented.
+func FromComparer(c diff.Comparer) diffapi.DiffServer {
+	return FromApplierAndComparer(nil, c)
+}
+
 func (s *service) Apply(ctx context.Context, er *diffapi.ApplyRequest) (*diffapi.ApplyResponse, error) {
 	if s.applier == nil {
 		return nil, errdefs.ToGRPC(errdefs.ErrNotImplemented)
@@ -0,0 +1,96 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package diffservice
+
+import (
+	"context"
+	"testing"
+
+	diffapi "github.com/containerd/containerd/v2/api/services/diff/v1"
+	"github.com/containerd/containerd/v2/api/types"
+	"github.com/containerd/containerd/v2/core/diff"
+	"github.com/containerd/containerd/v2/core/mount"
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type stubComparer struct {
+	desc ocispec.Descriptor
+}
+
+func (s stubComparer) Compare(ctx context.Context, lower, upper []mount.Mount, opts ...diff.Opt) (ocispec.Descriptor, error) {
+	return s.desc, nil
+}
+
+type stubApplier struct {
+	desc ocispec.Descriptor
+}
+
+func (s stubApplier) Apply(ctx context.Context, desc ocispec.Descriptor, mounts []mount.Mount, opts ...diff.ApplyOpt) (ocispec.Descriptor, error) {
+	return s.desc, nil
+}
+
+func TestFromComparer(t *testing.T) {
+	ctx := context.Background()
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayerGzip,
+		Digest:    digest.FromString("diff"),
+		Size:      42,
+	}
+	svc := FromComparer(stubComparer{desc: desc})
+
+	resp, err := svc.Diff(ctx, &diffapi.DiffRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Diff.Digest != desc.Digest.String() {
+		t.Errorf("unexpected digest %s", resp.Diff.Digest)
+	}
+
+	if _, err := svc.Apply(ctx, &diffapi.ApplyRequest{}); !errdefs.IsNotImplemented(errdefs.FromGRPC(err)) {
+		t.Errorf("expected ErrNotImplemented from Apply, got %v", err)
+	}
+}
+
+func TestFromApplier(t *testing.T) {
+	ctx := context.Background()
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayerGzip,
+		Digest:    digest.FromString("applied"),
+		Size:      42,
+	}
+	svc := FromApplier(stubApplier{desc: desc})
+
+	resp, err := svc.Apply(ctx, &diffapi.ApplyRequest{
+		Diff: &types.Descriptor{
+			MediaType: desc.MediaType,
+			Digest:    desc.Digest.String(),
+			Size:      desc.Size,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Applied.Digest != desc.Digest.String() {
+		t.Errorf("unexpected digest %s", resp.Applied.Digest)
+	}
+
+	if _, err := svc.Diff(ctx, &diffapi.DiffRequest{}); !errdefs.IsNotImplemented(errdefs.FromGRPC(err)) {
+		t.Errorf("expected ErrNotImplemented from Diff, got %v", err)
+	}
+}
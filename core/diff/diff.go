@@ -18,6 +18,7 @@ package diff
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"time"
 
@@ -48,8 +49,28 @@ type Config struct {
 
 	// SourceDateEpoch specifies the SOURCE_DATE_EPOCH without touching the env vars.
 	SourceDateEpoch *time.Time
+
+	// FileFilter reports whether a path should be included in the diff.
+	// A nil filter includes every path. Enforcement is up to the differ;
+	// differs which do not support filtering ignore it.
+	FileFilter func(path string) bool
+
+	// WhiteoutFormat selects how deletions are encoded in the diff,
+	// either WhiteoutAUFS or WhiteoutOverlay. An empty value means
+	// WhiteoutAUFS, the format used by OCI layer archives.
+	WhiteoutFormat string
 }
 
+const (
+	// WhiteoutAUFS encodes deletions as AUFS-style .wh. prefixed files,
+	// the format used by OCI and Docker layer archives.
+	WhiteoutAUFS = "aufs"
+
+	// WhiteoutOverlay encodes deletions as overlayfs character devices
+	// with 0/0 device numbers.
+	WhiteoutOverlay = "overlayfs"
+)
+
 // Opt is used to configure a diff operation
 type Opt func(*Config) error
 
@@ -135,6 +156,32 @@ func WithSyncFs(sync bool) ApplyOpt {
 	}
 }
 
+// WithFileFilter sets a predicate deciding which paths are included in the
+// computed diff. Paths for which f returns false are skipped. A nil filter
+// includes all paths. Support is differ-dependent.
+func WithFileFilter(f func(path string) bool) Opt {
+	return func(c *Config) error {
+		c.FileFilter = f
+		return nil
+	}
+}
+
+// WithWhiteoutFormat selects the whiteout format used to encode deletions in
+// the computed diff, either WhiteoutAUFS or WhiteoutOverlay. The default is
+// WhiteoutAUFS. Support is differ-dependent; differs which only produce one
+// format ignore it.
+func WithWhiteoutFormat(format string) Opt {
+	return func(c *Config) error {
+		switch format {
+		case WhiteoutAUFS, WhiteoutOverlay:
+		default:
+			return fmt.Errorf("unsupported whiteout format %q", format)
+		}
+		c.WhiteoutFormat = format
+		return nil
+	}
+}
+
 // WithSourceDateEpoch specifies the timestamp used to provide control for reproducibility.
 // See also https://reproducible-builds.org/docs/source-date-epoch/ .
 //
@@ -0,0 +1,90 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithFileFilter(t *testing.T) {
+	var config Config
+	filter := func(p string) bool {
+		return !strings.HasPrefix(p, "/tmp")
+	}
+	if err := WithFileFilter(filter)(&config); err != nil {
+		t.Fatal(err)
+	}
+	if config.FileFilter == nil {
+		t.Fatal("expected FileFilter to be set")
+	}
+	if config.FileFilter("/tmp/scratch") {
+		t.Error("expected /tmp/scratch to be excluded")
+	}
+	if !config.FileFilter("/etc/hosts") {
+		t.Error("expected /etc/hosts to be included")
+	}
+}
+
+func TestFileFilterDefaultsToNil(t *testing.T) {
+	var config Config
+	for _, opt := range []Opt{
+		WithMediaType("application/vnd.oci.image.layer.v1.tar"),
+		WithReference("test-ref"),
+	} {
+		if err := opt(&config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A nil filter means every path is included; differs must not require
+	// the field to be populated.
+	if config.FileFilter != nil {
+		t.Error("expected FileFilter to remain nil when not configured")
+	}
+}
+
+func TestWithWhiteoutFormat(t *testing.T) {
+	for _, format := range []string{WhiteoutAUFS, WhiteoutOverlay} {
+		t.Run(format, func(t *testing.T) {
+			var config Config
+			if err := WithWhiteoutFormat(format)(&config); err != nil {
+				t.Fatal(err)
+			}
+			if config.WhiteoutFormat != format {
+				t.Errorf("expected whiteout format %q, got %q", format, config.WhiteoutFormat)
+			}
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		var config Config
+		if err := WithWhiteoutFormat("tarfs")(&config); err == nil {
+			t.Fatal("expected error for unsupported whiteout format")
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		var config Config
+		if err := WithMediaType("application/vnd.oci.image.layer.v1.tar")(&config); err != nil {
+			t.Fatal(err)
+		}
+		// An empty value means AUFS, the format of OCI layer archives.
+		if config.WhiteoutFormat != "" {
+			t.Errorf("expected empty whiteout format by default, got %q", config.WhiteoutFormat)
+		}
+	})
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/containerd/errdefs"
 	"github.com/containerd/log"
 	"github.com/containerd/platforms"
+	distref "github.com/distribution/reference"
 	digest "github.com/opencontainers/go-digest"
 	ocispecs "github.com/opencontainers/image-spec/specs-go"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -42,6 +43,14 @@ type exportOptions struct {
 	platform           platforms.MatchComparer
 	allPlatforms       bool
 	skipDockerManifest bool
+	legacyRepositories bool
+	dockerTags         map[digest.Digest][]string
+	stripAnnotations   []string
+	baseImage          *ocispec.Descriptor
+	sortBlobs          bool
+	manifestFilter     ManifestFilter
+	checksumManifest   bool
+	findSBOM           func(images.Image) (ocispec.Descriptor, bool)
 	blobRecordOptions  blobRecordOptions
 }
 
@@ -77,6 +86,16 @@ func WithSkipDockerManifest() ExportOpt {
 	}
 }
 
+// WithLegacyRepositoriesFile writes the legacy `repositories` file read by
+// very old docker load implementations, mapping each exported repository and
+// tag to the image's top layer ID, alongside the Docker manifest.json.
+func WithLegacyRepositoriesFile() ExportOpt {
+	return func(ctx context.Context, o *exportOptions) error {
+		o.legacyRepositories = true
+		return nil
+	}
+}
+
 // WithImage adds the provided images to the exported archive.
 func WithImage(is images.Store, name string) ExportOpt {
 	return func(ctx context.Context, o *exportOptions) error {
@@ -124,6 +143,111 @@ func WithManifest(manifest ocispec.Descriptor, names ...string) ExportOpt {
 	}
 }
 
+// WithDockerTags overrides the RepoTags emitted in the Docker manifest.json
+// for the given manifest digests, allowing an image to be exported under
+// different tags than its stored names. Tags for digests not included in the
+// export are ignored.
+func WithDockerTags(tags map[digest.Digest][]string) ExportOpt {
+	return func(ctx context.Context, o *exportOptions) error {
+		for dgst, names := range tags {
+			for _, name := range names {
+				if _, err := normalizeReference(name); err != nil {
+					return fmt.Errorf("invalid tag for %s: %w", dgst, err)
+				}
+			}
+		}
+		o.dockerTags = tags
+		return nil
+	}
+}
+
+// WithStripAnnotations removes annotation keys matching any of the given
+// prefixes from the exported descriptors, so ephemeral annotations such as
+// build-cache hints do not travel with a distributed artifact. Called with
+// no arguments it strips the "containerd.io/gc." prefix. Non-matching
+// annotations are kept.
+func WithStripAnnotations(prefixes ...string) ExportOpt {
+	return func(ctx context.Context, o *exportOptions) error {
+		if len(prefixes) == 0 {
+			prefixes = []string{"containerd.io/gc."}
+		}
+		o.stripAnnotations = append(o.stripAnnotations, prefixes...)
+		return nil
+	}
+}
+
+// stripAnnotations returns annotations without the keys matching any of the
+// prefixes, or nil when nothing remains.
+func stripAnnotations(annotations map[string]string, prefixes []string) map[string]string {
+	if len(annotations) == 0 || len(prefixes) == 0 {
+		return annotations
+	}
+	stripped := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		matched := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(k, prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			stripped[k] = v
+		}
+	}
+	if len(stripped) == 0 {
+		return nil
+	}
+	return stripped
+}
+
+// WithSortBlobs emits blob records sorted purely by their encoded digest,
+// independent of digest algorithm grouping, so multiple exports of
+// overlapping images produce a stable blob ordering for downstream streaming
+// dedup. The default ordering sorts records by archive path, which groups
+// blobs by algorithm first. The archive remains a valid, importable image
+// bundle either way.
+func WithSortBlobs() ExportOpt {
+	return func(ctx context.Context, o *exportOptions) error {
+		o.sortBlobs = true
+		return nil
+	}
+}
+
+// AnnotationDeltaBase is set on the index of a delta archive produced with
+// WithBaseImage, recording the digest of the base image whose layers were
+// omitted.
+const AnnotationDeltaBase = "containerd.io/export.delta-base"
+
+// WithBaseImage exports a delta archive relative to the given base image:
+// layer blobs shared with the base are omitted while manifests and configs
+// are kept intact, so a consumer which already holds the base can reassemble
+// the full image. The archive index is annotated with AnnotationDeltaBase to
+// mark it as a delta.
+func WithBaseImage(baseDesc ocispec.Descriptor) ExportOpt {
+	return func(ctx context.Context, o *exportOptions) error {
+		o.baseImage = &baseDesc
+		return nil
+	}
+}
+
+// ManifestFilter returns false if the manifest should not be included in the
+// archive.
+type ManifestFilter func(ocispec.Descriptor) bool
+
+// WithManifestFilter drops whole manifests from the export: the filter is
+// consulted for each candidate manifest — both those given directly and those
+// reached through an index — before its blobs are collected. Dropping a
+// manifest drops the blobs only it references, while blobs shared with a kept
+// manifest remain in the archive. Composes with WithPlatform, which is
+// applied independently.
+func WithManifestFilter(f ManifestFilter) ExportOpt {
+	return func(ctx context.Context, o *exportOptions) error {
+		o.manifestFilter = f
+		return nil
+	}
+}
+
 // BlobFilter returns false if the blob should not be included in the archive.
 type BlobFilter func(ocispec.Descriptor) bool
 
@@ -143,6 +267,43 @@ func WithSkipNonDistributableBlobs() ExportOpt {
 	return WithBlobFilter(f)
 }
 
+// WithChecksumManifest writes an extra "checksums.json" record to the
+// archive listing every exported blob's path, digest, and size, so a
+// consumer can verify the archive's blobs without a full import. The file
+// is archive metadata and is not referenced by the OCI index.
+func WithChecksumManifest() ExportOpt {
+	return func(ctx context.Context, o *exportOptions) error {
+		o.checksumManifest = true
+		return nil
+	}
+}
+
+// WithSBOMExtraction writes the SBOM located by the callback as a plain
+// top-level "sbom.json" record, in addition to the blob's normal place in
+// the OCI content, so consumers can read it without walking the layout. The
+// callback is invoked per exported image and the first hit is extracted.
+func WithSBOMExtraction(find func(images.Image) (ocispec.Descriptor, bool)) ExportOpt {
+	return func(ctx context.Context, o *exportOptions) error {
+		o.findSBOM = find
+		return nil
+	}
+}
+
+// ExportProgressFunc is called as each blob record streams into the
+// archive, with the cumulative bytes written for that descriptor, and once
+// more when the record completes. Callbacks run on the export goroutine and
+// should return promptly.
+type ExportProgressFunc func(desc ocispec.Descriptor, written int64)
+
+// WithExportProgress reports per-blob progress through fn as records are
+// written, keyed by the blob descriptor. A nil fn is a no-op.
+func WithExportProgress(fn ExportProgressFunc) ExportOpt {
+	return func(ctx context.Context, o *exportOptions) error {
+		o.blobRecordOptions.progress = fn
+		return nil
+	}
+}
+
 // WithSkipMissing excludes blobs referenced by manifests if not all blobs
 // would be included in the archive.
 // The manifest itself is excluded only if it's not present locally.
@@ -220,6 +381,25 @@ func Export(ctx context.Context, store content.InfoReaderProvider, writer io.Wri
 		}
 	}
 
+	if eo.baseImage != nil {
+		baseLayers, err := layerDigests(ctx, store, *eo.baseImage)
+		if err != nil {
+			return fmt.Errorf("failed to resolve base image layers: %w", err)
+		}
+		prev := eo.blobRecordOptions.blobFilter
+		eo.blobRecordOptions.blobFilter = func(desc ocispec.Descriptor) bool {
+			if prev != nil && !prev(desc) {
+				return false
+			}
+			if images.IsLayerType(desc.MediaType) {
+				if _, shared := baseLayers[desc.Digest]; shared {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
 	records := []tarRecord{
 		ociLayoutFile(""),
 	}
@@ -231,6 +411,10 @@ func Export(ctx context.Context, store content.InfoReaderProvider, writer io.Wri
 			log.G(ctx).WithError(err).WithField("desc", desc).Warn("failed to copy distribution.source labels")
 			continue
 		}
+		d.Annotations = stripAnnotations(d.Annotations, eo.stripAnnotations)
+		if images.IsManifestType(d.MediaType) && eo.manifestFilter != nil && !eo.manifestFilter(d) {
+			continue
+		}
 		manifests = append(manifests, d)
 	}
 
@@ -278,6 +462,9 @@ func Export(ctx context.Context, store content.InfoReaderProvider, writer io.Wri
 
 				var manifests []ocispec.Descriptor
 				for _, m := range index.Manifests {
+					if eo.manifestFilter != nil && !eo.manifestFilter(m) {
+						continue
+					}
 					if eo.platform != nil {
 						if m.Platform == nil || eo.platform.Match(*m.Platform) {
 							manifests = append(manifests, m)
@@ -327,15 +514,45 @@ func Export(ctx context.Context, store content.InfoReaderProvider, writer io.Wri
 		}
 	}
 
-	records = append(records, ociIndexRecord(manifests))
+	for i, desc := range manifests {
+		if desc.Platform != nil || !images.IsManifestType(desc.MediaType) {
+			continue
+		}
+		p, err := manifestPlatform(ctx, store, desc)
+		if err != nil {
+			log.G(ctx).WithError(err).WithField("desc", desc).Warn("failed to resolve platform for index record")
+			continue
+		}
+		manifests[i].Platform = &p
+	}
+
+	var indexAnnotations map[string]string
+	if eo.baseImage != nil {
+		indexAnnotations = map[string]string{AnnotationDeltaBase: eo.baseImage.Digest.String()}
+	}
+	records = append(records, ociIndexRecord(manifests, indexAnnotations))
 
 	if !eo.skipDockerManifest && len(dManifests) > 0 {
+		for d, names := range eo.dockerTags {
+			if mt, ok := dManifests[d]; ok {
+				mt.names = names
+			}
+		}
 		tr, err := manifestsRecord(ctx, store, dManifests)
 		if err != nil {
 			return fmt.Errorf("unable to create manifests file: %w", err)
 		}
 
 		records = append(records, tr)
+
+		if eo.legacyRepositories {
+			rr, err := repositoriesRecord(ctx, store, dManifests)
+			if err != nil {
+				return fmt.Errorf("unable to create repositories file: %w", err)
+			}
+
+			records = append(records, rr)
+		}
 	}
 
 	if len(algorithms) > 0 {
@@ -345,9 +562,61 @@ func Export(ctx context.Context, store content.InfoReaderProvider, writer io.Wri
 		}
 	}
 
+	if eo.findSBOM != nil {
+		for _, desc := range manifests {
+			img := images.Image{
+				Name:   desc.Annotations[images.AnnotationImageName],
+				Target: desc,
+			}
+			if sbom, ok := eo.findSBOM(img); ok {
+				records = append(records, sbomRecord(store, sbom))
+				break
+			}
+		}
+	}
+
+	if eo.checksumManifest {
+		records = append(records, checksumsRecord(records))
+	}
+
 	tw := tar.NewWriter(writer)
 	defer tw.Close()
-	return writeTar(ctx, tw, records)
+	return writeTar(ctx, tw, records, eo.sortBlobs)
+}
+
+// manifestPlatform resolves the platform of a manifest by reading the config
+// it references, allowing exported index records to carry platform
+// information for multi-arch archives.
+func manifestPlatform(ctx context.Context, store content.Provider, desc ocispec.Descriptor) (ocispec.Platform, error) {
+	p, err := content.ReadBlob(ctx, store, desc)
+	if err != nil {
+		return ocispec.Platform{}, err
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		return ocispec.Platform{}, err
+	}
+	return images.ConfigPlatform(ctx, store, manifest.Config)
+}
+
+// layerDigests walks the image at desc and returns the set of layer digests
+// it references across all platforms.
+func layerDigests(ctx context.Context, store content.Provider, desc ocispec.Descriptor) (map[digest.Digest]struct{}, error) {
+	layers := map[digest.Digest]struct{}{}
+	handlers := images.Handlers(
+		images.ChildrenHandler(store),
+		images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+			if images.IsLayerType(desc.MediaType) {
+				layers[desc.Digest] = struct{}{}
+			}
+			return nil, nil
+		}),
+	)
+	if err := images.Walk(ctx, handlers, desc); err != nil {
+		return nil, err
+	}
+	return layers, nil
 }
 
 func getRecords(ctx context.Context, store content.Provider, desc ocispec.Descriptor, algorithms map[string]struct{}, brOpts *blobRecordOptions) ([]tarRecord, error) {
@@ -380,6 +649,17 @@ func getRecords(ctx context.Context, store content.Provider, desc ocispec.Descri
 	return records, nil
 }
 
+// blobSortKey strips the algorithm component from blob paths so blobs sort
+// purely by encoded digest; other record names are unchanged.
+func blobSortKey(name string) string {
+	if rest, ok := strings.CutPrefix(name, ocispec.ImageBlobsDir+"/"); ok {
+		if _, enc, ok := strings.Cut(rest, "/"); ok && enc != "" {
+			return path.Join(ocispec.ImageBlobsDir, enc)
+		}
+	}
+	return name
+}
+
 type tarRecord struct {
 	Header *tar.Header
 	CopyTo func(context.Context, io.Writer) (int64, error)
@@ -388,12 +668,17 @@ type tarRecord struct {
 type blobRecordOptions struct {
 	blobFilter      BlobFilter
 	childrenHandler images.HandlerFunc
+	progress        ExportProgressFunc
 }
 
 func blobRecord(cs content.Provider, desc ocispec.Descriptor, opts *blobRecordOptions) tarRecord {
 	if opts != nil && opts.blobFilter != nil && !opts.blobFilter(desc) {
 		return tarRecord{}
 	}
+	var progress ExportProgressFunc
+	if opts != nil {
+		progress = opts.progress
+	}
 	return tarRecord{
 		Header: &tar.Header{
 			Name:     path.Join(ocispec.ImageBlobsDir, desc.Digest.Algorithm().String(), desc.Digest.Encoded()),
@@ -408,9 +693,123 @@ func blobRecord(cs content.Provider, desc ocispec.Descriptor, opts *blobRecordOp
 			}
 			defer r.Close()
 
+			if progress != nil {
+				w = &progressWriter{w: w, desc: desc, fn: progress}
+			}
+
 			// Verify digest
 			dgstr := desc.Digest.Algorithm().Digester()
 
+			n, err := io.Copy(io.MultiWriter(w, dgstr.Hash()), content.NewReader(r))
+			if err != nil {
+				return 0, fmt.Errorf("failed to copy to tar: %w", err)
+			}
+			if dgstr.Digest() != desc.Digest {
+				return 0, fmt.Errorf("unexpected digest %s copied", dgstr.Digest())
+			}
+			if progress != nil {
+				progress(desc, n)
+			}
+			return n, nil
+		},
+	}
+}
+
+// progressWriter reports the cumulative bytes written for one blob record.
+// The store reader is not held locked during the callback; bytes have
+// already been copied out when it runs.
+type progressWriter struct {
+	w       io.Writer
+	desc    ocispec.Descriptor
+	fn      ExportProgressFunc
+	written int64
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.written += int64(n)
+	if n > 0 {
+		pw.fn(pw.desc, pw.written)
+	}
+	return n, err
+}
+
+// checksumEntry describes one blob of the archive in the checksum manifest.
+type checksumEntry struct {
+	Path   string        `json:"path"`
+	Digest digest.Digest `json:"digest"`
+	Size   int64         `json:"size"`
+}
+
+// checksumsRecord builds the "checksums.json" record from the blob records
+// collected so far, deduplicating shared blobs and sorting by path for a
+// stable output.
+func checksumsRecord(records []tarRecord) tarRecord {
+	seen := map[string]struct{}{}
+	entries := []checksumEntry{}
+	for _, r := range records {
+		if r.Header == nil || r.Header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := r.Header.Name
+		if !strings.HasPrefix(name, ocispec.ImageBlobsDir+"/") {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		parts := strings.Split(name, "/")
+		if len(parts) != 3 {
+			continue
+		}
+		dgst := digest.NewDigestFromEncoded(digest.Algorithm(parts[1]), parts[2])
+		if dgst.Validate() != nil {
+			continue
+		}
+		entries = append(entries, checksumEntry{Path: name, Digest: dgst, Size: r.Header.Size})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		panic(err)
+	}
+
+	return tarRecord{
+		Header: &tar.Header{
+			Name:     "checksums.json",
+			Mode:     0444,
+			Size:     int64(len(b)),
+			Typeflag: tar.TypeReg,
+		},
+		CopyTo: func(ctx context.Context, w io.Writer) (int64, error) {
+			n, err := w.Write(b)
+			return int64(n), err
+		},
+	}
+}
+
+// sbomRecord writes the SBOM blob under the fixed top-level name
+// "sbom.json", verifying its digest as it is copied out.
+func sbomRecord(cs content.Provider, desc ocispec.Descriptor) tarRecord {
+	return tarRecord{
+		Header: &tar.Header{
+			Name:     "sbom.json",
+			Mode:     0444,
+			Size:     desc.Size,
+			Typeflag: tar.TypeReg,
+		},
+		CopyTo: func(ctx context.Context, w io.Writer) (int64, error) {
+			r, err := cs.ReaderAt(ctx, desc)
+			if err != nil {
+				return 0, fmt.Errorf("failed to get reader: %w", err)
+			}
+			defer r.Close()
+
+			dgstr := desc.Digest.Algorithm().Digester()
 			n, err := io.Copy(io.MultiWriter(w, dgstr.Hash()), content.NewReader(r))
 			if err != nil {
 				return 0, fmt.Errorf("failed to copy to tar: %w", err)
@@ -461,13 +860,14 @@ func ociLayoutFile(version string) tarRecord {
 
 }
 
-func ociIndexRecord(manifests []ocispec.Descriptor) tarRecord {
+func ociIndexRecord(manifests []ocispec.Descriptor, annotations map[string]string) tarRecord {
 	index := ocispec.Index{
 		Versioned: ocispecs.Versioned{
 			SchemaVersion: 2,
 		},
-		MediaType: ocispec.MediaTypeImageIndex,
-		Manifests: manifests,
+		MediaType:   ocispec.MediaTypeImageIndex,
+		Manifests:   manifests,
+		Annotations: annotations,
 	}
 
 	b, err := json.Marshal(index)
@@ -495,13 +895,18 @@ type exportManifest struct {
 }
 
 func manifestsRecord(ctx context.Context, store content.Provider, manifests map[digest.Digest]*exportManifest) (tarRecord, error) {
-	mfsts := make([]struct {
+	type mfstEntry struct {
 		Config   string
 		RepoTags []string
 		Layers   []string
-	}, len(manifests))
-
-	var i int
+	}
+	var (
+		mfsts []mfstEntry
+		// Manifests with the same config and layer set (e.g. the same image
+		// serialized with different annotations) are collapsed into a single
+		// entry carrying all tags, matching `docker save` behavior.
+		byContent = map[string]int{}
+	)
 	for _, m := range manifests {
 		p, err := content.ReadBlob(ctx, store, m.manifest)
 		if err != nil {
@@ -517,9 +922,10 @@ func manifestsRecord(ctx context.Context, store content.Provider, manifests map[
 		if err := dgst.Validate(); err != nil {
 			return tarRecord{}, err
 		}
-		mfsts[i].Config = path.Join(ocispec.ImageBlobsDir, dgst.Algorithm().String(), dgst.Encoded())
+		var entry mfstEntry
+		entry.Config = path.Join(ocispec.ImageBlobsDir, dgst.Algorithm().String(), dgst.Encoded())
 		for _, l := range manifest.Layers {
-			mfsts[i].Layers = append(mfsts[i].Layers, path.Join(ocispec.ImageBlobsDir, l.Digest.Algorithm().String(), l.Digest.Encoded()))
+			entry.Layers = append(entry.Layers, path.Join(ocispec.ImageBlobsDir, l.Digest.Algorithm().String(), l.Digest.Encoded()))
 		}
 
 		for _, name := range m.names {
@@ -528,10 +934,16 @@ func manifestsRecord(ctx context.Context, store content.Provider, manifests map[
 				return tarRecord{}, err
 			}
 
-			mfsts[i].RepoTags = append(mfsts[i].RepoTags, nname)
+			entry.RepoTags = append(entry.RepoTags, nname)
 		}
 
-		i++
+		key := entry.Config + "\n" + strings.Join(entry.Layers, "\n")
+		if i, ok := byContent[key]; ok {
+			mfsts[i].RepoTags = append(mfsts[i].RepoTags, entry.RepoTags...)
+			continue
+		}
+		byContent[key] = len(mfsts)
+		mfsts = append(mfsts, entry)
 	}
 
 	b, err := json.Marshal(mfsts)
@@ -553,15 +965,81 @@ func manifestsRecord(ctx context.Context, store content.Provider, manifests map[
 	}, nil
 }
 
-func writeTar(ctx context.Context, tw *tar.Writer, recordsWithEmpty []tarRecord) error {
+// repositoriesRecord synthesizes the legacy `repositories` file
+// (`{repo: {tag: layerID}}`) from the exported manifests, where layerID is
+// the digest of the image's top layer.
+func repositoriesRecord(ctx context.Context, store content.Provider, manifests map[digest.Digest]*exportManifest) (tarRecord, error) {
+	repos := map[string]map[string]string{}
+	for _, m := range manifests {
+		p, err := content.ReadBlob(ctx, store, m.manifest)
+		if err != nil {
+			return tarRecord{}, err
+		}
+
+		var manifest ocispec.Manifest
+		if err := json.Unmarshal(p, &manifest); err != nil {
+			return tarRecord{}, err
+		}
+		if len(manifest.Layers) == 0 {
+			continue
+		}
+		topLayer := manifest.Layers[len(manifest.Layers)-1].Digest
+		if err := topLayer.Validate(); err != nil {
+			return tarRecord{}, err
+		}
+
+		for _, name := range m.names {
+			named, err := distref.ParseNormalizedNamed(name)
+			if err != nil {
+				return tarRecord{}, fmt.Errorf("failed to parse %q: %w", name, err)
+			}
+			named = distref.TagNameOnly(named)
+			tagged, ok := named.(distref.Tagged)
+			if !ok {
+				continue
+			}
+			repo := distref.FamiliarName(named)
+			if repos[repo] == nil {
+				repos[repo] = map[string]string{}
+			}
+			repos[repo][tagged.Tag()] = topLayer.Encoded()
+		}
+	}
+
+	b, err := json.Marshal(repos)
+	if err != nil {
+		return tarRecord{}, err
+	}
+
+	return tarRecord{
+		Header: &tar.Header{
+			Name:     "repositories",
+			Mode:     0644,
+			Size:     int64(len(b)),
+			Typeflag: tar.TypeReg,
+		},
+		CopyTo: func(ctx context.Context, w io.Writer) (int64, error) {
+			n, err := w.Write(b)
+			return int64(n), err
+		},
+	}, nil
+}
+
+func writeTar(ctx context.Context, tw *tar.Writer, recordsWithEmpty []tarRecord, sortBlobs bool) error {
 	var records []tarRecord
 	for _, r := range recordsWithEmpty {
 		if r.Header != nil {
 			records = append(records, r)
 		}
 	}
+	sortKey := func(name string) string {
+		if sortBlobs {
+			return blobSortKey(name)
+		}
+		return name
+	}
 	sort.Slice(records, func(i, j int) bool {
-		return records[i].Header.Name < records[j].Header.Name
+		return sortKey(records[i].Header.Name) < sortKey(records[j].Header.Name)
 	})
 
 	var last string
@@ -587,3 +1065,143 @@ func writeTar(ctx context.Context, tw *tar.Writer, recordsWithEmpty []tarRecord)
 	}
 	return nil
 }
+
+// AppendToArchive writes a combined archive to out containing everything in
+// the existing OCI layout tar plus the given manifests and their blobs,
+// without re-exporting content already present. Blobs already in the archive
+// are skipped and the resulting index.json holds the union of the existing
+// and new manifest entries. Options apply to the newly added manifests only;
+// a Docker manifest.json in the existing archive is copied through unchanged
+// and does not cover the appended images.
+func AppendToArchive(ctx context.Context, store content.InfoReaderProvider, existing io.ReadSeeker, out io.Writer, newManifests []ocispec.Descriptor, opts ...ExportOpt) error {
+	var eo exportOptions
+	for _, opt := range opts {
+		if err := opt(ctx, &eo); err != nil {
+			return err
+		}
+	}
+	newManifests = append(newManifests, eo.manifests...)
+
+	if _, err := existing.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(out)
+	defer tw.Close()
+
+	// Copy the existing archive through, holding back index.json for the
+	// merge below.
+	var (
+		index ocispec.Index
+		found bool
+		seen  = map[string]struct{}{}
+	)
+	tr := tar.NewReader(existing)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Name == ocispec.ImageIndexFile {
+			p, err := io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(p, &index); err != nil {
+				return fmt.Errorf("invalid %s in existing archive: %w", ocispec.ImageIndexFile, err)
+			}
+			found = true
+			continue
+		}
+		seen[hdr.Name] = struct{}{}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("existing archive has no %s: %w", ocispec.ImageIndexFile, errdefs.ErrInvalidArgument)
+	}
+
+	inIndex := map[digest.Digest]struct{}{}
+	for _, m := range index.Manifests {
+		inIndex[m.Digest] = struct{}{}
+	}
+
+	algorithms := map[string]struct{}{}
+	var records []tarRecord
+	for _, desc := range newManifests {
+		if !images.IsManifestType(desc.MediaType) && !images.IsIndexType(desc.MediaType) {
+			return fmt.Errorf("only manifests may be appended: %w", errdefs.ErrInvalidArgument)
+		}
+		d, err := copySourceLabels(ctx, store, desc)
+		if err != nil {
+			log.G(ctx).WithError(err).WithField("desc", desc).Warn("failed to copy distribution.source labels")
+			d = desc
+		}
+		d.Annotations = stripAnnotations(d.Annotations, eo.stripAnnotations)
+
+		r, err := getRecords(ctx, store, d, algorithms, &eo.blobRecordOptions)
+		if err != nil {
+			return err
+		}
+		records = append(records, r...)
+
+		if _, ok := inIndex[d.Digest]; !ok {
+			inIndex[d.Digest] = struct{}{}
+			index.Manifests = append(index.Manifests, d)
+		}
+	}
+
+	// Drop blobs the existing archive already carries.
+	var fresh []tarRecord
+	for _, r := range records {
+		if r.Header == nil {
+			continue
+		}
+		if _, ok := seen[r.Header.Name]; ok {
+			continue
+		}
+		fresh = append(fresh, r)
+	}
+	for alg := range algorithms {
+		if _, ok := seen["blobs/"+alg+"/"]; !ok {
+			fresh = append(fresh, directoryRecord("blobs/"+alg+"/", 0755))
+		}
+	}
+	fresh = append(fresh, mergedIndexRecord(index))
+
+	return writeTar(ctx, tw, fresh, eo.sortBlobs)
+}
+
+// mergedIndexRecord serializes an already-populated index, unlike
+// ociIndexRecord which builds one from manifest descriptors.
+func mergedIndexRecord(index ocispec.Index) tarRecord {
+	if index.SchemaVersion == 0 {
+		index.SchemaVersion = 2
+	}
+	if index.MediaType == "" {
+		index.MediaType = ocispec.MediaTypeImageIndex
+	}
+	b, err := json.Marshal(index)
+	if err != nil {
+		panic(err)
+	}
+	return tarRecord{
+		Header: &tar.Header{
+			Name:     ocispec.ImageIndexFile,
+			Mode:     0644,
+			Size:     int64(len(b)),
+			Typeflag: tar.TypeReg,
+		},
+		CopyTo: func(ctx context.Context, w io.Writer) (int64, error) {
+			n, err := w.Write(b)
+			return int64(n), err
+		},
+	}
+}
@@ -0,0 +1,817 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// memoryStore is a minimal in-memory content store for exercising export
+// code paths in tests.
+type memoryStore struct {
+	blobs map[digest.Digest][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{blobs: map[digest.Digest][]byte{}}
+}
+
+func (s *memoryStore) add(mediaType string, b []byte) ocispec.Descriptor {
+	dgst := digest.FromBytes(b)
+	s.blobs[dgst] = b
+	return ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    dgst,
+		Size:      int64(len(b)),
+	}
+}
+
+func (s *memoryStore) addJSON(t *testing.T, mediaType string, v interface{}) ocispec.Descriptor {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.add(mediaType, b)
+}
+
+func (s *memoryStore) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (content.ReaderAt, error) {
+	b, ok := s.blobs[desc.Digest]
+	if !ok {
+		return nil, errdefs.ErrNotFound
+	}
+	return &memoryReaderAt{Reader: bytes.NewReader(b)}, nil
+}
+
+func (s *memoryStore) Info(ctx context.Context, dgst digest.Digest) (content.Info, error) {
+	b, ok := s.blobs[dgst]
+	if !ok {
+		return content.Info{}, errdefs.ErrNotFound
+	}
+	return content.Info{
+		Digest:    dgst,
+		Size:      int64(len(b)),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+type memoryReaderAt struct {
+	*bytes.Reader
+}
+
+func (r *memoryReaderAt) Close() error { return nil }
+
+// testImage writes a minimal image (config, one layer, manifest) into the
+// store and returns the manifest descriptor.
+func testImage(t *testing.T, store *memoryStore) ocispec.Descriptor {
+	t.Helper()
+	config := store.addJSON(t, ocispec.MediaTypeImageConfig, ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+	})
+	layer := store.add(ocispec.MediaTypeImageLayerGzip, []byte("layer-data"))
+	return store.addJSON(t, ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layer},
+	})
+}
+
+// readTarEntry returns the content of the named file in the tar archive, or
+// nil if the entry is not present.
+func readTarEntry(t *testing.T, b []byte, name string) []byte {
+	t.Helper()
+	tr := tar.NewReader(bytes.NewReader(b))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name == name {
+			p, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		}
+	}
+}
+
+func TestExportDockerTagOverride(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	manifest := testImage(t, store)
+
+	var buf bytes.Buffer
+	err := Export(ctx, store, &buf,
+		WithManifest(manifest, "docker.io/internal/app:original"),
+		WithDockerTags(map[digest.Digest][]string{
+			manifest.Digest: {"app:release"},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := readTarEntry(t, buf.Bytes(), "manifest.json")
+	if p == nil {
+		t.Fatal("manifest.json missing from archive")
+	}
+
+	var mfsts []struct {
+		Config   string
+		RepoTags []string
+	}
+	if err := json.Unmarshal(p, &mfsts); err != nil {
+		t.Fatal(err)
+	}
+	if len(mfsts) != 1 {
+		t.Fatalf("expected 1 manifest entry, got %d", len(mfsts))
+	}
+	if len(mfsts[0].RepoTags) != 1 || mfsts[0].RepoTags[0] != "app:release" {
+		t.Errorf("unexpected RepoTags %v, expected [app:release]", mfsts[0].RepoTags)
+	}
+}
+
+func TestWithDockerTagsInvalidReference(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	manifest := testImage(t, store)
+
+	err := Export(ctx, store, io.Discard,
+		WithManifest(manifest, "docker.io/internal/app:original"),
+		WithDockerTags(map[digest.Digest][]string{
+			manifest.Digest: {"UPPERCASE:Bad:Tag"},
+		}),
+	)
+	if err == nil {
+		t.Fatal("expected error for invalid tag reference")
+	}
+}
+
+// testImageForArch is like testImage with a specific architecture in the
+// config.
+func testImageForArch(t *testing.T, store *memoryStore, arch string) ocispec.Descriptor {
+	t.Helper()
+	config := store.addJSON(t, ocispec.MediaTypeImageConfig, ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: arch},
+	})
+	layer := store.add(ocispec.MediaTypeImageLayerGzip, []byte("layer-data-"+arch))
+	return store.addJSON(t, ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layer},
+	})
+}
+
+func TestExportIndexPlatforms(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	amd64 := testImageForArch(t, store, "amd64")
+	arm64 := testImageForArch(t, store, "arm64")
+
+	var buf bytes.Buffer
+	err := Export(ctx, store, &buf,
+		WithManifest(amd64, "docker.io/internal/app:amd64"),
+		WithManifest(arm64, "docker.io/internal/app:arm64"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := readTarEntry(t, buf.Bytes(), ocispec.ImageIndexFile)
+	if p == nil {
+		t.Fatal("index.json missing from archive")
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(p, &idx); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Manifests) != 2 {
+		t.Fatalf("expected 2 manifests, got %d", len(idx.Manifests))
+	}
+	archs := map[string]bool{}
+	for _, m := range idx.Manifests {
+		if m.Platform == nil {
+			t.Fatalf("manifest %s has no platform", m.Digest)
+		}
+		if m.Platform.OS != "linux" {
+			t.Errorf("unexpected OS %q for %s", m.Platform.OS, m.Digest)
+		}
+		archs[m.Platform.Architecture] = true
+	}
+	if !archs["amd64"] || !archs["arm64"] {
+		t.Errorf("expected amd64 and arm64 platforms, got %v", archs)
+	}
+}
+
+func TestExportLegacyRepositoriesFile(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	manifest := testImage(t, store)
+
+	var buf bytes.Buffer
+	err := Export(ctx, store, &buf,
+		WithManifest(manifest, "docker.io/internal/app:release"),
+		WithLegacyRepositoriesFile(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := readTarEntry(t, buf.Bytes(), "repositories")
+	if p == nil {
+		t.Fatal("repositories missing from archive")
+	}
+
+	var repos map[string]map[string]string
+	if err := json.Unmarshal(p, &repos); err != nil {
+		t.Fatal(err)
+	}
+	tags, ok := repos["internal/app"]
+	if !ok {
+		t.Fatalf("expected repository internal/app, got %v", repos)
+	}
+
+	// The layer ID must be the digest of the image's top (last) layer.
+	mp := readTarEntry(t, buf.Bytes(), "blobs/sha256/"+manifest.Digest.Encoded())
+	if mp == nil {
+		t.Fatal("manifest blob missing from archive")
+	}
+	var m ocispec.Manifest
+	if err := json.Unmarshal(mp, &m); err != nil {
+		t.Fatal(err)
+	}
+	topLayer := m.Layers[len(m.Layers)-1].Digest.Encoded()
+	if tags["release"] != topLayer {
+		t.Errorf("unexpected layer ID %q, expected %q", tags["release"], topLayer)
+	}
+}
+
+func TestExportStripAnnotations(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	manifest := testImage(t, store)
+	manifest.Annotations = map[string]string{
+		"containerd.io/gc.ref.content.0":    "sha256:aaaa",
+		"buildkit.cache.ref":                "cache-key",
+		"org.opencontainers.image.revision": "abc123",
+	}
+
+	var buf bytes.Buffer
+	err := Export(ctx, store, &buf,
+		WithManifest(manifest, "registry.test/stripped:latest"),
+		WithStripAnnotations("containerd.io/gc.", "buildkit."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexJSON := readTarEntry(t, buf.Bytes(), "index.json")
+	if indexJSON == nil {
+		t.Fatal("index.json missing from archive")
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(indexJSON, &idx); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Manifests) != 1 {
+		t.Fatalf("expected 1 manifest, got %d", len(idx.Manifests))
+	}
+
+	annotations := idx.Manifests[0].Annotations
+	for _, k := range []string{"containerd.io/gc.ref.content.0", "buildkit.cache.ref"} {
+		if _, ok := annotations[k]; ok {
+			t.Errorf("expected annotation %q to be stripped", k)
+		}
+	}
+	if annotations["org.opencontainers.image.revision"] != "abc123" {
+		t.Error("expected non-matching annotation to survive")
+	}
+	if annotations[images.AnnotationImageName] != "registry.test/stripped:latest" {
+		t.Error("expected image name annotation to survive")
+	}
+}
+
+func TestStripAnnotationsDefault(t *testing.T) {
+	ctx := context.Background()
+	var eo exportOptions
+	if err := WithStripAnnotations()(ctx, &eo); err != nil {
+		t.Fatal(err)
+	}
+	in := map[string]string{
+		"containerd.io/gc.root": "true",
+		"keep":                  "1",
+	}
+	out := stripAnnotations(in, eo.stripAnnotations)
+	if _, ok := out["containerd.io/gc.root"]; ok {
+		t.Error("expected default prefix containerd.io/gc. to be stripped")
+	}
+	if out["keep"] != "1" {
+		t.Error("expected other annotations to remain")
+	}
+}
+
+func TestExportWithBaseImage(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+
+	shared := store.add(ocispec.MediaTypeImageLayerGzip, []byte("shared-base-layer"))
+	newImage := func(extra string) ocispec.Descriptor {
+		config := store.addJSON(t, ocispec.MediaTypeImageConfig, ocispec.Image{
+			Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		})
+		layer := store.add(ocispec.MediaTypeImageLayerGzip, []byte("unique-layer-"+extra))
+		return store.addJSON(t, ocispec.MediaTypeImageManifest, ocispec.Manifest{
+			Versioned: ocispecs.Versioned{SchemaVersion: 2},
+			MediaType: ocispec.MediaTypeImageManifest,
+			Config:    config,
+			Layers:    []ocispec.Descriptor{shared, layer},
+		})
+	}
+	base := newImage("base")
+	updated := newImage("updated")
+
+	var buf bytes.Buffer
+	err := Export(ctx, store, &buf,
+		WithManifest(updated, "registry.test/app:delta"),
+		WithBaseImage(base),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blobPath := func(d digest.Digest) string {
+		return "blobs/" + d.Algorithm().String() + "/" + d.Encoded()
+	}
+	if readTarEntry(t, buf.Bytes(), blobPath(shared.Digest)) != nil {
+		t.Error("expected shared layer to be omitted from delta archive")
+	}
+	if readTarEntry(t, buf.Bytes(), blobPath(updated.Digest)) == nil {
+		t.Error("expected manifest blob to be present")
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(store.blobs[updated.Digest], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if readTarEntry(t, buf.Bytes(), blobPath(manifest.Config.Digest)) == nil {
+		t.Error("expected config blob to be present")
+	}
+	if readTarEntry(t, buf.Bytes(), blobPath(manifest.Layers[1].Digest)) == nil {
+		t.Error("expected unique layer to be present")
+	}
+
+	indexJSON := readTarEntry(t, buf.Bytes(), "index.json")
+	if indexJSON == nil {
+		t.Fatal("index.json missing from archive")
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(indexJSON, &idx); err != nil {
+		t.Fatal(err)
+	}
+	if idx.Annotations[AnnotationDeltaBase] != base.Digest.String() {
+		t.Errorf("expected delta base annotation %s, got %v", base.Digest, idx.Annotations)
+	}
+}
+
+func TestExportSortBlobs(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	amd64 := testImageForArch(t, store, "amd64")
+	arm64 := testImageForArch(t, store, "arm64")
+
+	var buf bytes.Buffer
+	err := Export(ctx, store, &buf,
+		WithManifest(amd64, "registry.test/app:amd64"),
+		WithManifest(arm64, "registry.test/app:arm64"),
+		WithSortBlobs(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var encoded []string
+	tr := tar.NewReader(bytes.NewReader(buf.Bytes()))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasPrefix(hdr.Name, "blobs/") {
+			continue
+		}
+		encoded = append(encoded, hdr.Name[strings.LastIndex(hdr.Name, "/")+1:])
+	}
+	if len(encoded) < 6 {
+		t.Fatalf("expected at least 6 blobs, got %d", len(encoded))
+	}
+	if !sort.StringsAreSorted(encoded) {
+		t.Errorf("expected blobs in sorted digest order, got %v", encoded)
+	}
+
+	// The sorted archive must still import cleanly.
+	if err := ValidateArchive(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Errorf("expected sorted archive to validate, got %v", err)
+	}
+}
+
+func TestAppendToArchive(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	first := testImageForArch(t, store, "amd64")
+	second := testImageForArch(t, store, "arm64")
+
+	var base bytes.Buffer
+	if err := Export(ctx, store, &base, WithManifest(first, "registry.test/app:amd64")); err != nil {
+		t.Fatal(err)
+	}
+
+	second.Annotations = addNameAnnotation("registry.test/app:arm64", second.Annotations)
+	var combined bytes.Buffer
+	err := AppendToArchive(ctx, store, bytes.NewReader(base.Bytes()), &combined,
+		[]ocispec.Descriptor{second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexJSON := readTarEntry(t, combined.Bytes(), "index.json")
+	if indexJSON == nil {
+		t.Fatal("index.json missing from combined archive")
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(indexJSON, &idx); err != nil {
+		t.Fatal(err)
+	}
+	digests := map[digest.Digest]bool{}
+	for _, m := range idx.Manifests {
+		digests[m.Digest] = true
+	}
+	if !digests[first.Digest] || !digests[second.Digest] {
+		t.Errorf("expected index union to contain both manifests, got %v", idx.Manifests)
+	}
+
+	// Both images must be importable from the combined archive.
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := ImportIndex(ctx, cs, bytes.NewReader(combined.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []ocispec.Descriptor{first, second} {
+		ok, err := content.Exists(ctx, cs, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("expected manifest %s to be imported", d.Digest)
+		}
+	}
+	if desc.MediaType != ocispec.MediaTypeImageIndex {
+		t.Errorf("unexpected media type %q for imported index", desc.MediaType)
+	}
+
+	// Appending the same manifest again keeps the index deduplicated.
+	var again bytes.Buffer
+	err = AppendToArchive(ctx, store, bytes.NewReader(combined.Bytes()), &again,
+		[]ocispec.Descriptor{second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idx2 ocispec.Index
+	if err := json.Unmarshal(readTarEntry(t, again.Bytes(), "index.json"), &idx2); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx2.Manifests) != len(idx.Manifests) {
+		t.Errorf("expected no duplicate index entries, got %d vs %d", len(idx2.Manifests), len(idx.Manifests))
+	}
+}
+
+func TestExportDeduplicatesCompatibilityManifests(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+
+	// Two distinct manifest digests over the same config and layer set,
+	// differing only in annotations.
+	config := store.addJSON(t, ocispec.MediaTypeImageConfig, ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+	})
+	layer := store.add(ocispec.MediaTypeImageLayerGzip, []byte("layer-data"))
+	manifest := ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layer},
+	}
+	first := store.addJSON(t, ocispec.MediaTypeImageManifest, manifest)
+	manifest.Annotations = map[string]string{"org.opencontainers.image.created": "2024-01-01T00:00:00Z"}
+	second := store.addJSON(t, ocispec.MediaTypeImageManifest, manifest)
+	if first.Digest == second.Digest {
+		t.Fatal("expected distinct manifest digests")
+	}
+
+	var buf bytes.Buffer
+	err := Export(ctx, store, &buf,
+		WithManifest(first, "docker.io/library/app:v1"),
+		WithManifest(second, "docker.io/library/app:v2"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := readTarEntry(t, buf.Bytes(), "manifest.json")
+	if p == nil {
+		t.Fatal("manifest.json missing from archive")
+	}
+	var mfsts []struct {
+		Config   string
+		RepoTags []string
+	}
+	if err := json.Unmarshal(p, &mfsts); err != nil {
+		t.Fatal(err)
+	}
+	if len(mfsts) != 1 {
+		t.Fatalf("expected 1 compatibility entry, got %d", len(mfsts))
+	}
+	tags := map[string]bool{}
+	for _, tag := range mfsts[0].RepoTags {
+		tags[tag] = true
+	}
+	if len(tags) != 2 || !tags["app:v1"] || !tags["app:v2"] {
+		t.Errorf("unexpected RepoTags %v, expected both tags", mfsts[0].RepoTags)
+	}
+}
+
+func TestExportWithManifestFilter(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	amd64 := testImageForArch(t, store, "amd64")
+	arm64 := testImageForArch(t, store, "arm64")
+
+	var buf bytes.Buffer
+	err := Export(ctx, store, &buf,
+		WithManifest(amd64, "docker.io/internal/app:amd64"),
+		WithManifest(arm64, "docker.io/internal/app:arm64"),
+		WithManifestFilter(func(desc ocispec.Descriptor) bool {
+			return desc.Digest != arm64.Digest
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := readTarEntry(t, buf.Bytes(), ocispec.ImageIndexFile)
+	if p == nil {
+		t.Fatal("index.json missing from archive")
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(p, &idx); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Manifests) != 1 || idx.Manifests[0].Digest != amd64.Digest {
+		t.Fatalf("expected only the amd64 manifest in the index, got %v", idx.Manifests)
+	}
+
+	// The dropped manifest and its exclusive layer must be absent, the kept
+	// manifest's layer present.
+	if readTarEntry(t, buf.Bytes(), "blobs/sha256/"+arm64.Digest.Encoded()) != nil {
+		t.Error("filtered manifest blob present in archive")
+	}
+	armLayer := digest.FromBytes([]byte("layer-data-arm64"))
+	if readTarEntry(t, buf.Bytes(), "blobs/sha256/"+armLayer.Encoded()) != nil {
+		t.Error("filtered manifest's layer present in archive")
+	}
+	amdLayer := digest.FromBytes([]byte("layer-data-amd64"))
+	if readTarEntry(t, buf.Bytes(), "blobs/sha256/"+amdLayer.Encoded()) == nil {
+		t.Error("kept manifest's layer missing from archive")
+	}
+}
+
+func TestExportWithChecksumManifest(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	manifest := testImage(t, store)
+	manifest.Annotations = map[string]string{images.AnnotationImageName: "docker.io/library/app:v1"}
+
+	var buf bytes.Buffer
+	if err := Export(ctx, store, &buf,
+		WithManifest(manifest),
+		WithChecksumManifest(),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	b := readTarEntry(t, buf.Bytes(), "checksums.json")
+	if b == nil {
+		t.Fatal("expected checksums.json in archive")
+	}
+	var entries []struct {
+		Path   string        `json:"path"`
+		Digest digest.Digest `json:"digest"`
+		Size   int64         `json:"size"`
+	}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every blob written to the archive must be listed with a matching
+	// digest and size.
+	byPath := map[string]digest.Digest{}
+	for _, e := range entries {
+		byPath[e.Path] = e.Digest
+	}
+	tr := tar.NewReader(bytes.NewReader(buf.Bytes()))
+	var blobs int
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasPrefix(hdr.Name, "blobs/") {
+			continue
+		}
+		blobs++
+		dgst, ok := byPath[hdr.Name]
+		if !ok {
+			t.Errorf("blob %s missing from checksum manifest", hdr.Name)
+			continue
+		}
+		p, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := dgst.Algorithm().FromBytes(p); got != dgst {
+			t.Errorf("blob %s digests to %s, manifest says %s", hdr.Name, got, dgst)
+		}
+	}
+	if blobs == 0 {
+		t.Fatal("expected blobs in archive")
+	}
+	if len(entries) != blobs {
+		t.Errorf("expected %d checksum entries, got %d", blobs, len(entries))
+	}
+
+	// The checksum manifest is metadata, not content: the OCI index must
+	// not reference it.
+	var index ocispec.Index
+	if err := json.Unmarshal(readTarEntry(t, buf.Bytes(), "index.json"), &index); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range index.Manifests {
+		if _, ok := byPath["blobs/"+m.Digest.Algorithm().String()+"/"+m.Digest.Encoded()]; !ok {
+			t.Errorf("index manifest %s not covered by checksum manifest", m.Digest)
+		}
+	}
+}
+
+func TestExportWithSBOMExtraction(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	manifest := testImage(t, store)
+	manifest.Annotations = map[string]string{images.AnnotationImageName: "docker.io/library/app:v1"}
+
+	sbomContent := []byte(`{"spdxVersion":"SPDX-2.3","name":"app"}`)
+	sbomDesc := store.add("application/spdx+json", sbomContent)
+
+	var sawImage images.Image
+	var buf bytes.Buffer
+	if err := Export(ctx, store, &buf,
+		WithManifest(manifest),
+		WithSBOMExtraction(func(img images.Image) (ocispec.Descriptor, bool) {
+			sawImage = img
+			return sbomDesc, true
+		}),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if sawImage.Name != "docker.io/library/app:v1" || sawImage.Target.Digest != manifest.Digest {
+		t.Errorf("unexpected image passed to callback: %+v", sawImage)
+	}
+
+	b := readTarEntry(t, buf.Bytes(), "sbom.json")
+	if b == nil {
+		t.Fatal("expected sbom.json in archive")
+	}
+	if !bytes.Equal(b, sbomContent) {
+		t.Errorf("unexpected sbom.json content %q", b)
+	}
+
+	// The OCI index is unaffected by the extraction.
+	var index ocispec.Index
+	if err := json.Unmarshal(readTarEntry(t, buf.Bytes(), "index.json"), &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != 1 || index.Manifests[0].Digest != manifest.Digest {
+		t.Errorf("unexpected index manifests %v", index.Manifests)
+	}
+}
+
+func TestExportWithSBOMExtractionNoMatch(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	manifest := testImage(t, store)
+
+	var buf bytes.Buffer
+	if err := Export(ctx, store, &buf,
+		WithManifest(manifest),
+		WithSBOMExtraction(func(images.Image) (ocispec.Descriptor, bool) {
+			return ocispec.Descriptor{}, false
+		}),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if b := readTarEntry(t, buf.Bytes(), "sbom.json"); b != nil {
+		t.Error("expected no sbom.json when the callback finds nothing")
+	}
+}
+
+func TestExportWithProgress(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+
+	// A layer bigger than the copy buffer produces several callbacks.
+	bigLayer := store.add(ocispec.MediaTypeImageLayerGzip, bytes.Repeat([]byte("x"), 100*1024))
+	config := store.addJSON(t, ocispec.MediaTypeImageConfig, ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+	})
+	manifest := store.addJSON(t, ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{bigLayer},
+	})
+
+	progress := map[digest.Digest][]int64{}
+	var buf bytes.Buffer
+	if err := Export(ctx, store, &buf,
+		WithManifest(manifest),
+		WithExportProgress(func(desc ocispec.Descriptor, written int64) {
+			progress[desc.Digest] = append(progress[desc.Digest], written)
+		}),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, desc := range []ocispec.Descriptor{bigLayer, config, manifest} {
+		counts := progress[desc.Digest]
+		if len(counts) == 0 {
+			t.Errorf("expected progress for %s", desc.Digest)
+			continue
+		}
+		for i := 1; i < len(counts); i++ {
+			if counts[i] < counts[i-1] {
+				t.Errorf("progress for %s went backwards: %v", desc.Digest, counts)
+				break
+			}
+		}
+		if final := counts[len(counts)-1]; final != desc.Size {
+			t.Errorf("expected final count %d for %s, got %d", desc.Size, desc.Digest, final)
+		}
+	}
+	if len(progress[bigLayer.Digest]) < 2 {
+		t.Errorf("expected multiple callbacks for the large layer, got %v", progress[bigLayer.Digest])
+	}
+}
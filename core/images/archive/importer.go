@@ -26,9 +26,11 @@ import (
 	"fmt"
 	"io"
 	"path"
+	"sync"
 
 	"github.com/containerd/containerd/v2/core/content"
 	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/core/images/converter"
 	"github.com/containerd/containerd/v2/pkg/archive/compression"
 	"github.com/containerd/containerd/v2/pkg/labels"
 	"github.com/containerd/errdefs"
@@ -37,10 +39,14 @@ import (
 	digest "github.com/opencontainers/go-digest"
 	specs "github.com/opencontainers/image-spec/specs-go"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/errgroup"
 )
 
 type importOpts struct {
-	compress bool
+	compress     bool
+	concurrency  int
+	maxEntries   int
+	convertToOCI bool
 }
 
 // ImportOpt is an option for importing an OCI index
@@ -55,6 +61,62 @@ func WithImportCompression() ImportOpt {
 	}
 }
 
+// WithImportConcurrency dispatches blob commits (hashing and store writes)
+// to a bounded pool of n workers so they overlap with reading subsequent tar
+// entries. The tar stream itself is still consumed sequentially and the
+// import does not complete until every blob has committed. Each in-flight
+// blob is fully buffered in memory, so peak usage is up to n times the
+// largest layer; size n accordingly for large images. Values below two keep
+// the fully serial behavior.
+func WithImportConcurrency(n int) ImportOpt {
+	return func(io *importOpts) error {
+		if n < 0 {
+			return fmt.Errorf("import concurrency must not be negative: %d", n)
+		}
+		io.concurrency = n
+		return nil
+	}
+}
+
+// WithMaxEntries caps the number of entries read from the tar archive,
+// guarding against crafted archives with millions of tiny entries. Defaults
+// to DefaultMaxImportEntries.
+func WithMaxEntries(n int) ImportOpt {
+	return func(io *importOpts) error {
+		if n <= 0 {
+			return fmt.Errorf("max entries must be positive: %d", n)
+		}
+		io.maxEntries = n
+		return nil
+	}
+}
+
+// WithImportConvertToOCI rewrites the Docker media types of manifests,
+// configs and layers synthesized for Docker-format tarballs to their OCI
+// equivalents, producing a native OCI image without a separate conversion
+// step. Archives which are already OCI layouts are unaffected.
+func WithImportConvertToOCI() ImportOpt {
+	return func(io *importOpts) error {
+		io.convertToOCI = true
+		return nil
+	}
+}
+
+// DefaultMaxImportEntries is the default cap on the number of tar entries
+// read by ImportIndex, high enough for any reasonable image archive.
+const DefaultMaxImportEntries = 1 << 20
+
+// ImportResult describes a completed import: the index descriptor and
+// metadata about the archive it was read from.
+type ImportResult struct {
+	// Descriptor is the descriptor of the imported index.
+	Descriptor ocispec.Descriptor
+
+	// LayoutVersion is the version declared in the archive's oci-layout
+	// file, or empty for Docker-format tarballs which carry none.
+	LayoutVersion string
+}
+
 // ImportIndex imports an index from a tar archive image bundle
 //   - implements Docker v1.1, v1.2 and OCI v1.
 //   - prefers OCI v1 when provided
@@ -63,6 +125,13 @@ func WithImportCompression() ImportOpt {
 //     e.g. alpine:latest -> docker.io/library/alpine:latest
 //   - existing OCI reference names are untouched
 func ImportIndex(ctx context.Context, store content.Store, reader io.Reader, opts ...ImportOpt) (ocispec.Descriptor, error) {
+	res, err := ImportIndexWithResult(ctx, store, reader, opts...)
+	return res.Descriptor, err
+}
+
+// ImportIndexWithResult behaves as ImportIndex while additionally reporting
+// metadata about the archive, such as the OCI layout version encountered.
+func ImportIndexWithResult(ctx context.Context, store content.Store, reader io.Reader, opts ...ImportOpt) (ImportResult, error) {
 	var (
 		tr = tar.NewReader(reader)
 
@@ -79,17 +148,62 @@ func ImportIndex(ctx context.Context, store content.Store, reader io.Reader, opt
 
 	for _, o := range opts {
 		if err := o(&iopts); err != nil {
-			return ocispec.Descriptor{}, err
+			return ImportResult{}, err
 		}
 	}
 
+	if iopts.maxEntries == 0 {
+		iopts.maxEntries = DefaultMaxImportEntries
+	}
+
+	var (
+		eg      *errgroup.Group
+		egCtx   = ctx
+		cancel  context.CancelFunc
+		blobsMu sync.Mutex
+	)
+	if iopts.concurrency > 1 {
+		var cctx context.Context
+		cctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		eg, egCtx = errgroup.WithContext(cctx)
+		// eg.Go blocks once the limit is reached, providing back-pressure
+		// on the main reading loop.
+		eg.SetLimit(iopts.concurrency)
+	}
+
+	// fail stops the workers and waits for them to wind down before
+	// returning, so no blob commits continue against the caller's store
+	// after the function has returned. The reader-side error takes
+	// precedence over any cancellation errors from the workers.
+	fail := func(err error) (ImportResult, error) {
+		if eg != nil {
+			cancel()
+			eg.Wait() //nolint:errcheck // the reader-side error takes precedence
+		}
+		return ImportResult{}, err
+	}
+
+	var entries int
 	for {
+		if egCtx.Err() != nil {
+			if eg == nil {
+				// The caller's context was canceled.
+				return ImportResult{}, egCtx.Err()
+			}
+			// A worker failed or the caller canceled; stop reading
+			// and surface the error below.
+			break
+		}
 		hdr, err := tr.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return ocispec.Descriptor{}, err
+			return fail(err)
+		}
+		if entries++; entries > iopts.maxEntries {
+			return fail(fmt.Errorf("archive contains more than %d entries: %w", iopts.maxEntries, errdefs.ErrInvalidArgument))
 		}
 		if hdr.Typeflag == tar.TypeSymlink {
 			symlinks[hdr.Name] = path.Join(path.Dir(hdr.Name), hdr.Linkname)
@@ -106,16 +220,37 @@ func ImportIndex(ctx context.Context, store content.Store, reader io.Reader, opt
 		hdrName := path.Clean(hdr.Name)
 		if hdrName == ocispec.ImageLayoutFile {
 			if err = onUntarJSON(tr, &ociLayout); err != nil {
-				return ocispec.Descriptor{}, fmt.Errorf("untar oci layout %q: %w", hdr.Name, err)
+				return fail(fmt.Errorf("untar oci layout %q: %w", hdr.Name, err))
 			}
 		} else if hdrName == "manifest.json" {
 			if err = onUntarJSON(tr, &mfsts); err != nil {
-				return ocispec.Descriptor{}, fmt.Errorf("untar manifest %q: %w", hdr.Name, err)
+				return fail(fmt.Errorf("untar manifest %q: %w", hdr.Name, err))
 			}
+		} else if eg != nil {
+			// Buffer the entry so its commit can proceed on a worker
+			// while the next entry is read.
+			p, err := io.ReadAll(tr)
+			if err != nil {
+				return fail(fmt.Errorf("failed to read %q: %w", hdr.Name, err))
+			}
+			size := hdr.Size
+			eg.Go(func() error {
+				dgst, err := onUntarBlob(egCtx, bytes.NewReader(p), store, size, "tar-"+hdrName)
+				if err != nil {
+					return fmt.Errorf("failed to ingest %q: %w", hdrName, err)
+				}
+				blobsMu.Lock()
+				blobs[hdrName] = ocispec.Descriptor{
+					Digest: dgst,
+					Size:   size,
+				}
+				blobsMu.Unlock()
+				return nil
+			})
 		} else {
 			dgst, err := onUntarBlob(ctx, tr, store, hdr.Size, "tar-"+hdrName)
 			if err != nil {
-				return ocispec.Descriptor{}, fmt.Errorf("failed to ingest %q: %w", hdr.Name, err)
+				return ImportResult{}, fmt.Errorf("failed to ingest %q: %w", hdr.Name, err)
 			}
 
 			blobs[hdrName] = ocispec.Descriptor{
@@ -125,31 +260,44 @@ func ImportIndex(ctx context.Context, store content.Store, reader io.Reader, opt
 		}
 	}
 
+	if eg != nil {
+		// All blobs must be committed before any manifest referencing them
+		// is finalized.
+		if err := eg.Wait(); err != nil {
+			return ImportResult{}, err
+		}
+		// A canceled caller context does not necessarily surface through
+		// the group when no worker was in flight.
+		if err := ctx.Err(); err != nil {
+			return ImportResult{}, err
+		}
+	}
+
 	// If OCI layout was given, interpret the tar as an OCI layout.
 	// When not provided, the layout of the tar will be interpreted
 	// as Docker v1.1 or v1.2.
 	if ociLayout.Version != "" {
 		if ociLayout.Version != ocispec.ImageLayoutVersion {
-			return ocispec.Descriptor{}, fmt.Errorf("unsupported OCI version %s", ociLayout.Version)
+			return ImportResult{}, fmt.Errorf("unsupported OCI version %s", ociLayout.Version)
 		}
 
 		idx, ok := blobs[ocispec.ImageIndexFile]
 		if !ok {
-			return ocispec.Descriptor{}, fmt.Errorf("missing index.json in OCI layout %s", ocispec.ImageLayoutVersion)
+			return ImportResult{}, fmt.Errorf("missing index.json in OCI layout %s", ocispec.ImageLayoutVersion)
 		}
 
 		idx.MediaType = ocispec.MediaTypeImageIndex
-		return idx, nil
+		return ImportResult{Descriptor: idx, LayoutVersion: ociLayout.Version}, nil
 	}
 
 	if mfsts == nil {
-		return ocispec.Descriptor{}, errors.New("unrecognized image format")
+		return ImportResult{}, errors.New("unrecognized image format")
 	}
 
 	for name, linkname := range symlinks {
 		desc, ok := blobs[linkname]
 		if !ok {
-			return ocispec.Descriptor{}, fmt.Errorf("no target for symlink layer from %q to %q", name, linkname)
+			return ImportResult{}, fmt.Errorf("no target for symlink layer from %q to %q", name, linkname)
 		}
 		blobs[name] = desc
 	}
@@ -162,13 +310,27 @@ func ImportIndex(ctx context.Context, store content.Store, reader io.Reader, opt
 	for _, mfst := range mfsts {
 		config, ok := blobs[mfst.Config]
 		if !ok {
-			return ocispec.Descriptor{}, fmt.Errorf("image config %q not found", mfst.Config)
+			return ImportResult{}, fmt.Errorf("image config %q not found", mfst.Config)
+		}
+		if config.MediaType == "" {
+			// Older Docker-saved tarballs do not carry a media type for
+			// the config, default it so the synthesized manifest does not
+			// end up with an empty config media type.
+			config.MediaType = images.MediaTypeDockerSchema2Config
 		}
-		config.MediaType = images.MediaTypeDockerSchema2Config
 
 		layers, err := resolveLayers(ctx, store, mfst.Layers, blobs, iopts.compress)
 		if err != nil {
-			return ocispec.Descriptor{}, fmt.Errorf("failed to resolve layers: %w", err)
+			return ImportResult{}, fmt.Errorf("failed to resolve layers: %w", err)
+		}
+
+		mediaType := images.MediaTypeDockerSchema2Manifest
+		if iopts.convertToOCI {
+			mediaType = ocispec.MediaTypeImageManifest
+			config.MediaType = converter.ConvertDockerMediaTypeToOCI(config.MediaType)
+			for i := range layers {
+				layers[i].MediaType = converter.ConvertDockerMediaTypeToOCI(layers[i].MediaType)
+			}
 		}
 
 		manifest := struct {
@@ -178,19 +340,19 @@ func ImportIndex(ctx context.Context, store content.Store, reader io.Reader, opt
 			Layers        []ocispec.Descriptor `json:"layers"`
 		}{
 			SchemaVersion: 2,
-			MediaType:     images.MediaTypeDockerSchema2Manifest,
+			MediaType:     mediaType,
 			Config:        config,
 			Layers:        layers,
 		}
 
 		desc, err := writeManifest(ctx, store, manifest, manifest.MediaType)
 		if err != nil {
-			return ocispec.Descriptor{}, fmt.Errorf("write docker manifest: %w", err)
+			return ImportResult{}, fmt.Errorf("write docker manifest: %w", err)
 		}
 
 		imgPlatforms, err := images.Platforms(ctx, store, desc)
 		if err != nil {
-			return ocispec.Descriptor{}, fmt.Errorf("unable to resolve platform: %w", err)
+			return ImportResult{}, fmt.Errorf("unable to resolve platform: %w", err)
 		}
 		if len(imgPlatforms) > 0 {
 			// Only one platform can be resolved from non-index manifest,
@@ -218,7 +380,7 @@ func ImportIndex(ctx context.Context, store content.Store, reader io.Reader, opt
 
 				normalized, err := normalizeReference(ref)
 				if err != nil {
-					return ocispec.Descriptor{}, err
+					return ImportResult{}, err
 				}
 
 				mfstdesc.Annotations = map[string]string{
@@ -231,7 +393,11 @@ func ImportIndex(ctx context.Context, store content.Store, reader io.Reader, opt
 		}
 	}
 
-	return writeManifest(ctx, store, idx, ocispec.MediaTypeImageIndex)
+	desc, err := writeManifest(ctx, store, idx, ocispec.MediaTypeImageIndex)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return ImportResult{Descriptor: desc}, nil
 }
 
 const (
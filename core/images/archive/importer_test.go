@@ -0,0 +1,372 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// tarEntry is a single file for building archive fixtures in tests.
+type tarEntry struct {
+	name string
+	data []byte
+}
+
+func buildTar(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     e.name,
+			Mode:     0644,
+			Size:     int64(len(e.data)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// dockerTarFixture builds a minimal Docker-format (manifest.json) image tar
+// with one uncompressed layer and a config without a media type.
+func dockerTarFixture(t *testing.T, repoTags []string) []byte {
+	t.Helper()
+	configJSON, err := json.Marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"rootfs": map[string]interface{}{
+			"type":     "layers",
+			"diff_ids": []string{"sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := buildTar(t, []tarEntry{{name: "etc/hostname", data: []byte("test\n")}})
+
+	mfstJSON, err := json.Marshal([]map[string]interface{}{
+		{
+			"Config":   "config.json",
+			"RepoTags": repoTags,
+			"Layers":   []string{"layer.tar"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buildTar(t, []tarEntry{
+		{name: "config.json", data: configJSON},
+		{name: "layer.tar", data: layer},
+		{name: "manifest.json", data: mfstJSON},
+	})
+}
+
+func TestImportIndexDefaultsConfigMediaType(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := dockerTarFixture(t, []string{"app:latest"})
+	idxDesc, err := ImportIndex(ctx, cs, bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := content.ReadBlob(ctx, cs, idxDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(p, &idx); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Manifests) != 1 {
+		t.Fatalf("expected 1 manifest, got %d", len(idx.Manifests))
+	}
+
+	mp, err := content.ReadBlob(ctx, cs, idx.Manifests[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(mp, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Config.MediaType != images.MediaTypeDockerSchema2Config {
+		t.Errorf("unexpected config media type %q, expected %q", manifest.Config.MediaType, images.MediaTypeDockerSchema2Config)
+	}
+}
+
+func TestImportIndexCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := dockerTarFixture(t, []string{"app:latest"})
+	for _, opts := range [][]ImportOpt{nil, {WithImportConcurrency(4)}} {
+		if _, err := ImportIndex(ctx, cs, bytes.NewReader(archive), opts...); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	}
+}
+
+func TestImportIndexConvertToOCI(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := dockerTarFixture(t, []string{"app:latest"})
+	idxDesc, err := ImportIndex(ctx, cs, bytes.NewReader(archive), WithImportConvertToOCI())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := content.ReadBlob(ctx, cs, idxDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(p, &idx); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Manifests) != 1 {
+		t.Fatalf("expected 1 manifest, got %d", len(idx.Manifests))
+	}
+	if idx.Manifests[0].MediaType != ocispec.MediaTypeImageManifest {
+		t.Errorf("unexpected manifest media type %q, expected %q", idx.Manifests[0].MediaType, ocispec.MediaTypeImageManifest)
+	}
+
+	mp, err := content.ReadBlob(ctx, cs, idx.Manifests[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(mp, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.MediaType != ocispec.MediaTypeImageManifest {
+		t.Errorf("unexpected manifest media type %q, expected %q", manifest.MediaType, ocispec.MediaTypeImageManifest)
+	}
+	if manifest.Config.MediaType != ocispec.MediaTypeImageConfig {
+		t.Errorf("unexpected config media type %q, expected %q", manifest.Config.MediaType, ocispec.MediaTypeImageConfig)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != ocispec.MediaTypeImageLayer {
+			t.Errorf("unexpected layer media type %q, expected %q", layer.MediaType, ocispec.MediaTypeImageLayer)
+		}
+	}
+}
+
+func TestImportIndexConcurrent(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Multi-blob fixture: several distinct layers committed by the worker
+	// pool must all be present before the manifest is finalized.
+	const layerCount = 8
+	var (
+		entries    []tarEntry
+		layerFiles []string
+		diffIDs    []string
+	)
+	for i := 0; i < layerCount; i++ {
+		name := fmt.Sprintf("layer-%d.tar", i)
+		layer := buildTar(t, []tarEntry{{
+			name: fmt.Sprintf("etc/file-%d", i),
+			data: []byte(fmt.Sprintf("content %d\n", i)),
+		}})
+		entries = append(entries, tarEntry{name: name, data: layer})
+		layerFiles = append(layerFiles, name)
+		diffIDs = append(diffIDs, digest.FromBytes(layer).String())
+	}
+
+	configJSON, err := json.Marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"rootfs": map[string]interface{}{
+			"type":     "layers",
+			"diff_ids": diffIDs,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfstJSON, err := json.Marshal([]map[string]interface{}{
+		{
+			"Config":   "config.json",
+			"RepoTags": []string{"app:latest"},
+			"Layers":   layerFiles,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries = append(entries,
+		tarEntry{name: "config.json", data: configJSON},
+		tarEntry{name: "manifest.json", data: mfstJSON},
+	)
+	archive := buildTar(t, entries)
+
+	idxDesc, err := ImportIndex(ctx, cs, bytes.NewReader(archive), WithImportConcurrency(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := content.ReadBlob(ctx, cs, idxDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(p, &idx); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Manifests) != 1 {
+		t.Fatalf("expected 1 manifest, got %d", len(idx.Manifests))
+	}
+
+	mp, err := content.ReadBlob(ctx, cs, idx.Manifests[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(mp, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Layers) != layerCount {
+		t.Fatalf("expected %d layers, got %d", layerCount, len(manifest.Layers))
+	}
+	for i, l := range manifest.Layers {
+		if _, err := cs.Info(ctx, l.Digest); err != nil {
+			t.Errorf("layer %d (%s) missing from store: %v", i, l.Digest, err)
+		}
+	}
+}
+
+func TestWithImportConcurrencyNegative(t *testing.T) {
+	var iopts importOpts
+	if err := WithImportConcurrency(-1)(&iopts); err == nil {
+		t.Error("expected error for negative concurrency")
+	}
+}
+
+func TestWithMaxEntries(t *testing.T) {
+	ctx := context.Background()
+	store, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []tarEntry
+	for i := 0; i < 10; i++ {
+		entries = append(entries, tarEntry{
+			name: fmt.Sprintf("file-%d", i),
+			data: []byte("x"),
+		})
+	}
+	archive := buildTar(t, entries)
+
+	_, err = ImportIndex(ctx, store, bytes.NewReader(archive), WithMaxEntries(5))
+	if err == nil {
+		t.Fatal("expected error when entry cap is exceeded")
+	}
+	if !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument error, got %v", err)
+	}
+
+	// Under the cap the archive is read fully (and then rejected as an
+	// unrecognized image format, not for its entry count).
+	_, err = ImportIndex(ctx, store, bytes.NewReader(archive), WithMaxEntries(100))
+	if err == nil || errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected unrecognized format error, got %v", err)
+	}
+
+	if err := WithMaxEntries(0)(&importOpts{}); err == nil {
+		t.Error("expected error for non-positive entry cap")
+	}
+}
+
+func TestImportIndexWithResultLayoutVersion(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// OCI layout: export an image and re-import it.
+	store := newMemoryStore()
+	manifest := testImage(t, store)
+	var buf bytes.Buffer
+	if err := Export(ctx, store, &buf, WithManifest(manifest, "docker.io/library/app:latest")); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ImportIndexWithResult(ctx, cs, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.LayoutVersion != ocispec.ImageLayoutVersion {
+		t.Errorf("unexpected layout version %q, expected %q", res.LayoutVersion, ocispec.ImageLayoutVersion)
+	}
+	if res.Descriptor.MediaType != ocispec.MediaTypeImageIndex {
+		t.Errorf("unexpected media type %q", res.Descriptor.MediaType)
+	}
+
+	// Docker-format tarballs have no layout file.
+	res, err = ImportIndexWithResult(ctx, cs, bytes.NewReader(dockerTarFixture(t, []string{"app:latest"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.LayoutVersion != "" {
+		t.Errorf("expected empty layout version for docker tar, got %q", res.LayoutVersion)
+	}
+	if res.Descriptor.Digest == "" {
+		t.Error("expected index descriptor")
+	}
+}
@@ -0,0 +1,176 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package archive
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ValidateArchive checks that the tar stream read from reader is a
+// well-formed image archive without writing anything to a content store.
+// It understands the same formats as ImportIndex: an OCI layout or a
+// Docker v1.1/v1.2 archive. Blobs stored under a digest path have their
+// content verified against that digest as the stream passes, and the
+// metadata files are checked to only reference blobs present in the
+// archive. The first structural or digest error encountered is returned.
+func ValidateArchive(ctx context.Context, reader io.Reader) error {
+	var (
+		tr = tar.NewReader(reader)
+
+		ociLayout ocispec.ImageLayout
+		indexJSON []byte
+		mfsts     []struct {
+			Config   string
+			RepoTags []string
+			Layers   []string
+		}
+		symlinks = make(map[string]string)
+		files    = make(map[string]int64)
+	)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			symlinks[hdr.Name] = path.Join(path.Dir(hdr.Name), hdr.Linkname)
+		}
+
+		//nolint:staticcheck // TypeRegA is deprecated but we may still receive an external tar with TypeRegA
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		hdrName := path.Clean(hdr.Name)
+		if hdrName == ocispec.ImageLayoutFile {
+			if err = onUntarJSON(tr, &ociLayout); err != nil {
+				return fmt.Errorf("untar oci layout %q: %w", hdr.Name, err)
+			}
+			continue
+		}
+		if hdrName == "manifest.json" {
+			if err = onUntarJSON(tr, &mfsts); err != nil {
+				return fmt.Errorf("untar manifest %q: %w", hdr.Name, err)
+			}
+			continue
+		}
+
+		files[hdrName] = hdr.Size
+		if hdrName == ocispec.ImageIndexFile {
+			if indexJSON, err = io.ReadAll(io.LimitReader(tr, jsonLimit)); err != nil {
+				return fmt.Errorf("failed to read %q: %w", hdr.Name, err)
+			}
+			continue
+		}
+		if dgst, ok := blobPathDigest(hdrName); ok {
+			verifier := dgst.Verifier()
+			if _, err := io.Copy(verifier, tr); err != nil {
+				return fmt.Errorf("failed to read %q: %w", hdr.Name, err)
+			}
+			if !verifier.Verified() {
+				return fmt.Errorf("content of %q does not match digest %s", hdr.Name, dgst)
+			}
+		}
+	}
+
+	// If OCI layout was given, interpret the tar as an OCI layout.
+	// When not provided, the layout of the tar will be interpreted
+	// as Docker v1.1 or v1.2.
+	if ociLayout.Version != "" {
+		if ociLayout.Version != ocispec.ImageLayoutVersion {
+			return fmt.Errorf("unsupported OCI version %s", ociLayout.Version)
+		}
+		if indexJSON == nil {
+			return fmt.Errorf("missing index.json in OCI layout %s", ocispec.ImageLayoutVersion)
+		}
+		var idx ocispec.Index
+		if err := json.Unmarshal(indexJSON, &idx); err != nil {
+			return fmt.Errorf("invalid index.json: %w", err)
+		}
+		for _, desc := range idx.Manifests {
+			if err := desc.Digest.Validate(); err != nil {
+				return fmt.Errorf("invalid manifest digest in index.json: %w", err)
+			}
+			name := path.Join(ocispec.ImageBlobsDir, desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+			size, ok := files[name]
+			if !ok {
+				return fmt.Errorf("manifest %s referenced by index.json not found in archive", desc.Digest)
+			}
+			if size != desc.Size {
+				return fmt.Errorf("manifest %s is %d bytes, index.json expects %d", desc.Digest, size, desc.Size)
+			}
+		}
+		return nil
+	}
+
+	if mfsts == nil {
+		return errors.New("unrecognized image format")
+	}
+
+	for name, linkname := range symlinks {
+		size, ok := files[linkname]
+		if !ok {
+			return fmt.Errorf("no target for symlink layer from %q to %q", name, linkname)
+		}
+		files[name] = size
+	}
+
+	for _, mfst := range mfsts {
+		if _, ok := files[mfst.Config]; !ok {
+			return fmt.Errorf("image config %q not found", mfst.Config)
+		}
+		for _, layer := range mfst.Layers {
+			if _, ok := files[layer]; !ok {
+				return fmt.Errorf("layer %q not found", layer)
+			}
+		}
+	}
+
+	return nil
+}
+
+// blobPathDigest returns the digest encoded in a blobs/<algorithm>/<encoded>
+// path, reporting false for paths outside the blobs directory or with an
+// unrecognized digest.
+func blobPathDigest(name string) (digest.Digest, bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] != ocispec.ImageBlobsDir {
+		return "", false
+	}
+	dgst := digest.NewDigestFromEncoded(digest.Algorithm(parts[1]), parts[2])
+	if err := dgst.Validate(); err != nil {
+		return "", false
+	}
+	return dgst, true
+}
@@ -0,0 +1,134 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ociTarFixture builds a minimal OCI layout tar with one manifest, config
+// and layer. The returned entries may be mutated before building the tar.
+func ociTarFixture(t *testing.T) []tarEntry {
+	t.Helper()
+
+	blobEntry := func(data []byte) (ocispec.Descriptor, tarEntry) {
+		dgst := digest.FromBytes(data)
+		return ocispec.Descriptor{
+				Digest: dgst,
+				Size:   int64(len(data)),
+			}, tarEntry{
+				name: "blobs/" + dgst.Algorithm().String() + "/" + dgst.Encoded(),
+				data: data,
+			}
+	}
+
+	config := []byte(`{"architecture":"amd64","os":"linux"}`)
+	configDesc, configEntry := blobEntry(config)
+	configDesc.MediaType = ocispec.MediaTypeImageConfig
+
+	layer := buildTar(t, []tarEntry{{name: "etc/hostname", data: []byte("test\n")}})
+	layerDesc, layerEntry := blobEntry(layer)
+	layerDesc.MediaType = ocispec.MediaTypeImageLayer
+
+	mb, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc, manifestEntry := blobEntry(mb)
+	manifestDesc.MediaType = ocispec.MediaTypeImageManifest
+
+	ib, err := json.Marshal(ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{manifestDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []tarEntry{
+		{name: ocispec.ImageLayoutFile, data: []byte(`{"imageLayoutVersion":"` + ocispec.ImageLayoutVersion + `"}`)},
+		{name: "index.json", data: ib},
+		manifestEntry,
+		configEntry,
+		layerEntry,
+	}
+}
+
+func TestValidateArchive(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ValidOCI", func(t *testing.T) {
+		archive := buildTar(t, ociTarFixture(t))
+		if err := ValidateArchive(ctx, bytes.NewReader(archive)); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("ValidDocker", func(t *testing.T) {
+		archive := dockerTarFixture(t, []string{"registry.test/app:latest"})
+		if err := ValidateArchive(ctx, bytes.NewReader(archive)); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("CorruptLayer", func(t *testing.T) {
+		entries := ociTarFixture(t)
+		// Flip a byte in the layer blob without updating its path digest.
+		layer := entries[len(entries)-1]
+		corrupted := append([]byte{}, layer.data...)
+		corrupted[0] ^= 0xff
+		entries[len(entries)-1] = tarEntry{name: layer.name, data: corrupted}
+
+		err := ValidateArchive(ctx, bytes.NewReader(buildTar(t, entries)))
+		if err == nil {
+			t.Fatal("expected digest error for corrupt layer")
+		}
+		if !strings.Contains(err.Error(), "does not match digest") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("MissingManifest", func(t *testing.T) {
+		entries := ociTarFixture(t)
+		// Drop the manifest blob referenced by index.json.
+		err := ValidateArchive(ctx, bytes.NewReader(buildTar(t, append(entries[:2:2], entries[3:]...))))
+		if err == nil {
+			t.Fatal("expected error for missing manifest blob")
+		}
+	})
+
+	t.Run("UnrecognizedFormat", func(t *testing.T) {
+		archive := buildTar(t, []tarEntry{{name: "random.txt", data: []byte("not an image")}})
+		if err := ValidateArchive(ctx, bytes.NewReader(archive)); err == nil {
+			t.Fatal("expected error for unrecognized format")
+		}
+	})
+}
@@ -19,18 +19,27 @@ package converter
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/containerd/containerd/v2/core/content"
 	"github.com/containerd/containerd/v2/core/images"
 	"github.com/containerd/containerd/v2/core/leases"
 	"github.com/containerd/platforms"
+	"github.com/opencontainers/go-digest"
 )
 
 type convertOpts struct {
-	layerConvertFunc ConvertFunc
-	docker2oci       bool
-	indexConvertFunc ConvertFunc
-	platformMC       platforms.MatchComparer
+	layerConvertFunc    ConvertFunc
+	docker2oci          bool
+	indexConvertFunc    ConvertFunc
+	platformMC          platforms.MatchComparer
+	messageSizeLimit    int64
+	digestRecorder      *map[digest.Digest]digest.Digest
+	configMediaType     string
+	digestAlgorithm     digest.Algorithm
+	preserveAnnotations []string
+	dryRun              bool
 }
 
 // Opt is an option for Convert()
@@ -70,6 +79,89 @@ func WithIndexConvertFunc(fn ConvertFunc) Opt {
 	}
 }
 
+// WithMessageSizeLimit caps the size of index, manifest, and config blobs
+// read during conversion, guarding against a crafted image exhausting
+// memory. Defaults to DefaultMessageSizeLimit. Only applies to the default
+// index convert func.
+func WithMessageSizeLimit(bytes int64) Opt {
+	return func(copts *convertOpts) error {
+		if bytes <= 0 {
+			return fmt.Errorf("message size limit must be positive: %d", bytes)
+		}
+		copts.messageSizeLimit = bytes
+		return nil
+	}
+}
+
+// WithDigestRecorder records the digest of every blob rewritten during
+// conversion into the provided map, keyed by the old digest. The map covers
+// manifests, configs, and layers, letting callers which cached references by
+// digest remap them after conversion. The map is populated when Convert
+// returns successfully. Only applies to the default index convert func.
+func WithDigestRecorder(m *map[digest.Digest]digest.Digest) Opt {
+	return func(copts *convertOpts) error {
+		if m == nil {
+			return errors.New("digest recorder map must not be nil")
+		}
+		copts.digestRecorder = m
+		return nil
+	}
+}
+
+// WithConfigMediaType rewrites only the config descriptor's media type to
+// the given value, for registries which accept one format's layers but
+// require the other's config type. Layer and manifest media types remain
+// governed by the other options. Only applies to the default index convert
+// func.
+func WithConfigMediaType(mt string) Opt {
+	return func(copts *convertOpts) error {
+		if !images.IsConfigType(mt) {
+			return fmt.Errorf("unknown config media type %q", mt)
+		}
+		copts.configMediaType = mt
+		return nil
+	}
+}
+
+// WithDigestAlgorithm re-addresses every blob of the image under the given
+// digest algorithm: layers, configs, manifests, and indexes are recomputed
+// and stored under their new digests, with GC labels rebuilt to reference
+// them. Blob content is unchanged unless another option rewrites it. Only
+// applies to the default index convert func.
+func WithDigestAlgorithm(algo digest.Algorithm) Opt {
+	return func(copts *convertOpts) error {
+		if !algo.Available() {
+			return fmt.Errorf("digest algorithm %q is not available", algo)
+		}
+		copts.digestAlgorithm = algo
+		return nil
+	}
+}
+
+// WithPreserveAnnotations keeps the listed annotation keys on descriptors
+// whose annotations would otherwise be removed, such as Docker media types
+// which do not support annotations. An empty list preserves none, matching
+// the default behavior. Only applies to the default index convert func.
+func WithPreserveAnnotations(keys ...string) Opt {
+	return func(copts *convertOpts) error {
+		copts.preserveAnnotations = append(copts.preserveAnnotations, keys...)
+		return nil
+	}
+}
+
+// WithDryRun computes the would-be conversion result, including any layer
+// and config rewrites, without writing blobs or creating the destination
+// image. Convert returns the prospective image; a target equal to the source
+// image's means the conversion is a no-op. Reads still go to the real store,
+// so the source image must be fully present; rewritten blobs are buffered in
+// memory for the duration of the call.
+func WithDryRun() Opt {
+	return func(copts *convertOpts) error {
+		copts.dryRun = true
+		return nil
+	}
+}
+
 // Client is implemented by *containerd.Client .
 type Client interface {
 	WithLease(ctx context.Context, opts ...leases.Opt) (context.Context, func(context.Context) error, error)
@@ -88,8 +180,16 @@ func Convert(ctx context.Context, client Client, dstRef, srcRef string, opts ...
 	if copts.platformMC == nil {
 		copts.platformMC = platforms.All
 	}
+	var dc *defaultConverter
 	if copts.indexConvertFunc == nil {
-		copts.indexConvertFunc = DefaultIndexConvertFunc(copts.layerConvertFunc, copts.docker2oci, copts.platformMC)
+		dc = newDefaultConverter(copts.layerConvertFunc, copts.docker2oci, copts.platformMC, ConvertHooks{})
+		if copts.messageSizeLimit > 0 {
+			dc.messageSizeLimit = copts.messageSizeLimit
+		}
+		dc.configMediaType = copts.configMediaType
+		dc.digestAlgorithm = copts.digestAlgorithm
+		dc.preserveAnnotations = copts.preserveAnnotations
+		copts.indexConvertFunc = dc.convert
 	}
 
 	ctx, done, err := client.WithLease(ctx)
@@ -99,6 +199,9 @@ func Convert(ctx context.Context, client Client, dstRef, srcRef string, opts ...
 	defer done(ctx)
 
 	cs := client.ContentStore()
+	if copts.dryRun {
+		cs = newDryRunStore(cs)
+	}
 	is := client.ImageService()
 	srcImg, err := is.Get(ctx, srcRef)
 	if err != nil {
@@ -109,12 +212,24 @@ func Convert(ctx context.Context, client Client, dstRef, srcRef string, opts ...
 	if err != nil {
 		return nil, err
 	}
+	if copts.digestRecorder != nil && dc != nil {
+		m := make(map[digest.Digest]digest.Digest, len(dc.digestMap))
+		dc.digestMapMu.Lock()
+		for old, new := range dc.digestMap {
+			m[old] = new
+		}
+		dc.digestMapMu.Unlock()
+		*copts.digestRecorder = m
+	}
 
 	dstImg := srcImg
 	dstImg.Name = dstRef
 	if dstDesc != nil {
 		dstImg.Target = *dstDesc
 	}
+	if copts.dryRun {
+		return &dstImg, nil
+	}
 	var res images.Image
 	if dstRef != srcRef {
 		_ = is.Delete(ctx, dstRef)
@@ -21,11 +21,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 
 	"github.com/containerd/containerd/v2/core/content"
 	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/errdefs"
 	"github.com/containerd/log"
 	"github.com/containerd/platforms"
 	"github.com/opencontainers/go-digest"
@@ -39,13 +41,7 @@ type ConvertFunc func(ctx context.Context, cs content.Store, desc ocispec.Descri
 
 // DefaultIndexConvertFunc is the default convert func used by Convert.
 func DefaultIndexConvertFunc(layerConvertFunc ConvertFunc, docker2oci bool, platformMC platforms.MatchComparer) ConvertFunc {
-	c := &defaultConverter{
-		layerConvertFunc: layerConvertFunc,
-		docker2oci:       docker2oci,
-		platformMC:       platformMC,
-		diffIDMap:        make(map[digest.Digest]digest.Digest),
-	}
-	return c.convert
+	return newDefaultConverter(layerConvertFunc, docker2oci, platformMC, ConvertHooks{}).convert
 }
 
 // ConvertHookFunc is a callback function called during conversion of a blob.
@@ -60,23 +56,38 @@ type ConvertHooks struct {
 
 // IndexConvertFuncWithHook is the convert func used by Convert with hook functions support.
 func IndexConvertFuncWithHook(layerConvertFunc ConvertFunc, docker2oci bool, platformMC platforms.MatchComparer, hooks ConvertHooks) ConvertFunc {
-	c := &defaultConverter{
+	return newDefaultConverter(layerConvertFunc, docker2oci, platformMC, hooks).convert
+}
+
+// DefaultMessageSizeLimit is the default cap on the size of index, manifest,
+// and config blobs read by the default converter.
+const DefaultMessageSizeLimit = 8 * 1024 * 1024 // 8 MiB
+
+func newDefaultConverter(layerConvertFunc ConvertFunc, docker2oci bool, platformMC platforms.MatchComparer, hooks ConvertHooks) *defaultConverter {
+	return &defaultConverter{
 		layerConvertFunc: layerConvertFunc,
 		docker2oci:       docker2oci,
 		platformMC:       platformMC,
 		diffIDMap:        make(map[digest.Digest]digest.Digest),
+		digestMap:        make(map[digest.Digest]digest.Digest),
 		hooks:            hooks,
+		messageSizeLimit: DefaultMessageSizeLimit,
 	}
-	return c.convert
 }
 
 type defaultConverter struct {
-	layerConvertFunc ConvertFunc
-	docker2oci       bool
-	platformMC       platforms.MatchComparer
-	diffIDMap        map[digest.Digest]digest.Digest // key: old diffID, value: new diffID
-	diffIDMapMu      sync.RWMutex
-	hooks            ConvertHooks
+	layerConvertFunc    ConvertFunc
+	docker2oci          bool
+	platformMC          platforms.MatchComparer
+	diffIDMap           map[digest.Digest]digest.Digest // key: old diffID, value: new diffID
+	diffIDMapMu         sync.RWMutex
+	digestMap           map[digest.Digest]digest.Digest // key: old digest, value: new digest
+	digestMapMu         sync.Mutex
+	hooks               ConvertHooks
+	messageSizeLimit    int64
+	configMediaType     string
+	digestAlgorithm     digest.Algorithm
+	preserveAnnotations []string
 }
 
 // convert dispatches desc.MediaType and calls c.convert{Layer,Manifest,Index,Config}.
@@ -116,17 +127,41 @@ func (c *defaultConverter) convert(ctx context.Context, cs content.Store, desc o
 			newDesc.MediaType = ConvertDockerMediaTypeToOCI(newDesc.MediaType)
 		} else if (newDesc == nil && len(desc.Annotations) != 0) || (newDesc != nil && len(newDesc.Annotations) != 0) {
 			// Annotations is supported only on OCI manifest.
-			// We need to remove annotations for Docker media types.
+			// We need to remove annotations for Docker media types,
+			// keeping only keys explicitly preserved.
 			if newDesc == nil {
 				newDesc = copyDesc(desc)
 			}
-			newDesc.Annotations = nil
+			newDesc.Annotations = filterAnnotations(newDesc.Annotations, c.preserveAnnotations)
 		}
 	}
+	if newDesc != nil && newDesc.Digest != desc.Digest {
+		c.digestMapMu.Lock()
+		c.digestMap[desc.Digest] = newDesc.Digest
+		c.digestMapMu.Unlock()
+	}
 	log.G(ctx).WithField("old", desc).WithField("new", newDesc).Debugf("converted")
 	return newDesc, nil
 }
 
+// filterAnnotations returns only the annotations whose keys are listed in
+// keep, or nil when none remain.
+func filterAnnotations(annotations map[string]string, keep []string) map[string]string {
+	if len(annotations) == 0 || len(keep) == 0 {
+		return nil
+	}
+	var kept map[string]string
+	for _, k := range keep {
+		if v, ok := annotations[k]; ok {
+			if kept == nil {
+				kept = make(map[string]string)
+			}
+			kept[k] = v
+		}
+	}
+	return kept
+}
+
 func copyDesc(desc ocispec.Descriptor) *ocispec.Descriptor {
 	descCopy := desc
 	return &descCopy
@@ -136,10 +171,69 @@ func copyDesc(desc ocispec.Descriptor) *ocispec.Descriptor {
 //
 // c.layerConvertFunc can be nil, e.g., for converting Docker media types to OCI ones.
 func (c *defaultConverter) convertLayer(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (*ocispec.Descriptor, error) {
+	var (
+		newDesc *ocispec.Descriptor
+		err     error
+	)
 	if c.layerConvertFunc != nil {
-		return c.layerConvertFunc(ctx, cs, desc)
+		newDesc, err = c.layerConvertFunc(ctx, cs, desc)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return nil, nil
+	if c.digestAlgorithm != "" {
+		src := desc
+		if newDesc != nil {
+			src = *newDesc
+		}
+		redigested, err := c.redigest(ctx, cs, src)
+		if err != nil {
+			return nil, err
+		}
+		if redigested != nil {
+			newDesc = redigested
+		}
+	}
+	return newDesc, nil
+}
+
+// redigest copies a blob unchanged into the store under the digest of the
+// target algorithm, returning the rewritten descriptor, or nil when the blob
+// is already addressed by it.
+func (c *defaultConverter) redigest(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (*ocispec.Descriptor, error) {
+	if c.digestAlgorithm == "" || desc.Digest.Algorithm() == c.digestAlgorithm {
+		return nil, nil
+	}
+	info, err := cs.Info(ctx, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	// First pass computes the digest under the target algorithm, the second
+	// writes the blob with the expected digest known upfront, so the blob is
+	// never buffered in memory.
+	ra, err := cs.ReaderAt(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	digester := c.digestAlgorithm.Digester()
+	_, err = io.Copy(digester.Hash(), content.NewReader(ra))
+	ra.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	newDesc := desc
+	newDesc.Digest = digester.Digest()
+	ra, err = cs.ReaderAt(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer ra.Close()
+	ref := fmt.Sprintf("converter-redigest-%s", newDesc.Digest.String())
+	if err := content.WriteBlob(ctx, cs, ref, content.NewReader(ra), newDesc, content.WithLabels(info.Labels)); err != nil {
+		return nil, err
+	}
+	return &newDesc, nil
 }
 
 // convertManifest converts image manifests.
@@ -151,7 +245,7 @@ func (c *defaultConverter) convertManifest(ctx context.Context, cs content.Store
 		manifest ocispec.Manifest
 		modified bool
 	)
-	labels, err := readJSON(ctx, cs, &manifest, desc)
+	labels, err := c.readJSON(ctx, cs, &manifest, desc)
 	if err != nil {
 		return nil, err
 	}
@@ -216,9 +310,20 @@ func (c *defaultConverter) convertManifest(ctx context.Context, cs content.Store
 		manifest.Config = *newConfig
 		modified = true
 	}
+	if c.configMediaType != "" && manifest.Config.MediaType != c.configMediaType {
+		// The override applies last so it wins over any docker2oci rewrite
+		// of the config descriptor.
+		manifest.Config.MediaType = c.configMediaType
+		modified = true
+	}
+	if c.digestAlgorithm != "" && desc.Digest.Algorithm() != c.digestAlgorithm {
+		// The manifest itself must be re-addressed even if its content is
+		// otherwise unchanged.
+		modified = true
+	}
 
 	if modified {
-		return writeJSON(ctx, cs, &manifest, desc, labels)
+		return writeJSONWithAlgorithm(ctx, cs, &manifest, desc, labels, c.writeAlgorithm())
 	}
 	return nil, nil
 }
@@ -232,7 +337,7 @@ func (c *defaultConverter) convertIndex(ctx context.Context, cs content.Store, d
 		index    ocispec.Index
 		modified bool
 	)
-	labels, err := readJSON(ctx, cs, &index, desc)
+	labels, err := c.readJSON(ctx, cs, &index, desc)
 	if err != nil {
 		return nil, err
 	}
@@ -282,6 +387,9 @@ func (c *defaultConverter) convertIndex(ctx context.Context, cs content.Store, d
 	if err := eg.Wait(); err != nil {
 		return nil, err
 	}
+	if c.digestAlgorithm != "" && desc.Digest.Algorithm() != c.digestAlgorithm {
+		modified = true
+	}
 	if modified {
 		var newManifestsClean []ocispec.Descriptor
 		for i, m := range newManifests {
@@ -290,7 +398,7 @@ func (c *defaultConverter) convertIndex(ctx context.Context, cs content.Store, d
 			}
 		}
 		index.Manifests = newManifestsClean
-		return writeJSON(ctx, cs, &index, desc, labels)
+		return writeJSONWithAlgorithm(ctx, cs, &index, desc, labels, c.writeAlgorithm())
 	}
 	return nil, nil
 }
@@ -307,14 +415,14 @@ func (c *defaultConverter) convertConfig(ctx context.Context, cs content.Store,
 		modified bool
 	)
 
-	labels, err := readJSON(ctx, cs, &cfg, desc)
+	labels, err := c.readJSON(ctx, cs, &cfg, desc)
 	if err != nil {
 		return nil, err
 	}
 	if labels == nil {
 		labels = make(map[string]string)
 	}
-	if _, err := readJSON(ctx, cs, &cfgAsOCI, desc); err != nil {
+	if _, err := c.readJSON(ctx, cs, &cfgAsOCI, desc); err != nil {
 		return nil, err
 	}
 
@@ -344,9 +452,11 @@ func (c *defaultConverter) convertConfig(ctx context.Context, cs content.Store,
 		if _, err := clearDockerV1DummyID(cfg); err != nil {
 			return nil, err
 		}
-		return writeJSON(ctx, cs, &cfg, desc, labels)
+		return writeJSONWithAlgorithm(ctx, cs, &cfg, desc, labels, c.writeAlgorithm())
 	}
-	return nil, nil
+	// An unchanged config may still need re-addressing under the target
+	// digest algorithm.
+	return c.redigest(ctx, cs, desc)
 }
 
 // clearDockerV1DummyID clears the dummy values for legacy `.config.Image` and `.container_config.Image`.
@@ -382,13 +492,25 @@ func clearDockerV1DummyID(cfg DualConfig) (bool, error) {
 // Unmarshalled as map[string]*json.RawMessage to retain unknown fields on remarshalling.
 type DualConfig map[string]*json.RawMessage
 
-func readJSON(ctx context.Context, cs content.Store, x interface{}, desc ocispec.Descriptor) (map[string]string, error) {
+func (c *defaultConverter) readJSON(ctx context.Context, cs content.Store, x interface{}, desc ocispec.Descriptor) (map[string]string, error) {
+	if c.messageSizeLimit > 0 && desc.Size > c.messageSizeLimit {
+		return nil, fmt.Errorf("%s size %d exceeds message size limit of %d bytes: %w", desc.Digest, desc.Size, c.messageSizeLimit, errdefs.ErrInvalidArgument)
+	}
 	info, err := cs.Info(ctx, desc.Digest)
 	if err != nil {
 		return nil, err
 	}
 	labels := info.Labels
-	b, err := content.ReadBlob(ctx, cs, desc)
+	ra, err := cs.ReaderAt(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer ra.Close()
+	r := io.Reader(content.NewReader(ra))
+	if c.messageSizeLimit > 0 {
+		r = io.LimitReader(r, c.messageSizeLimit)
+	}
+	b, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
@@ -398,27 +520,32 @@ func readJSON(ctx context.Context, cs content.Store, x interface{}, desc ocispec
 	return labels, nil
 }
 
+// writeAlgorithm returns the digest algorithm used for blobs written by the
+// converter.
+func (c *defaultConverter) writeAlgorithm() digest.Algorithm {
+	if c.digestAlgorithm != "" {
+		return c.digestAlgorithm
+	}
+	return digest.SHA256
+}
+
 func writeJSON(ctx context.Context, cs content.Store, x interface{}, oldDesc ocispec.Descriptor, labels map[string]string) (*ocispec.Descriptor, error) {
+	return writeJSONWithAlgorithm(ctx, cs, x, oldDesc, labels, digest.SHA256)
+}
+
+func writeJSONWithAlgorithm(ctx context.Context, cs content.Store, x interface{}, oldDesc ocispec.Descriptor, labels map[string]string, algo digest.Algorithm) (*ocispec.Descriptor, error) {
 	b, err := json.Marshal(x)
 	if err != nil {
 		return nil, err
 	}
-	dgst := digest.SHA256.FromBytes(b)
-	ref := fmt.Sprintf("converter-write-json-%s", dgst.String())
-	w, err := content.OpenWriter(ctx, cs, content.WithRef(ref))
-	if err != nil {
-		return nil, err
-	}
-	if err := content.Copy(ctx, w, bytes.NewReader(b), int64(len(b)), dgst, content.WithLabels(labels)); err != nil {
-		w.Close()
-		return nil, err
-	}
-	if err := w.Close(); err != nil {
-		return nil, err
-	}
+	dgst := algo.FromBytes(b)
 	newDesc := oldDesc
 	newDesc.Size = int64(len(b))
 	newDesc.Digest = dgst
+	ref := fmt.Sprintf("converter-write-json-%s", dgst.String())
+	if err := content.WriteBlob(ctx, cs, ref, bytes.NewReader(b), newDesc, content.WithLabels(labels)); err != nil {
+		return nil, err
+	}
 	return &newDesc, nil
 }
 
@@ -452,3 +579,38 @@ func ClearGCLabels(labels map[string]string, dgst digest.Digest) {
 		}
 	}
 }
+
+// RebuildGCLabels reads the given manifest or index and returns the complete
+// content GC-ref label set for it, referencing the config, layer and child
+// manifest digests it currently points at. After a conversion which rewrote
+// many digests, callers can apply the result via content.Update to replace
+// any stale labels instead of patching them piecemeal with ClearGCLabels.
+func RebuildGCLabels(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (map[string]string, error) {
+	b, err := content.ReadBlob(ctx, cs, desc)
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string]string)
+	switch {
+	case images.IsManifestType(desc.MediaType):
+		var manifest ocispec.Manifest
+		if err := json.Unmarshal(b, &manifest); err != nil {
+			return nil, err
+		}
+		labels["containerd.io/gc.ref.content.config"] = manifest.Config.Digest.String()
+		for i, l := range manifest.Layers {
+			labels[fmt.Sprintf("containerd.io/gc.ref.content.l.%d", i)] = l.Digest.String()
+		}
+	case images.IsIndexType(desc.MediaType):
+		var index ocispec.Index
+		if err := json.Unmarshal(b, &index); err != nil {
+			return nil, err
+		}
+		for i, m := range index.Manifests {
+			labels[fmt.Sprintf("containerd.io/gc.ref.content.m.%d", i)] = m.Digest.String()
+		}
+	default:
+		return nil, fmt.Errorf("unexpected media type %q: %w", desc.MediaType, errdefs.ErrInvalidArgument)
+	}
+	return labels, nil
+}
@@ -0,0 +1,304 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"bytes"
+	"context"
+	_ "crypto/sha512"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/core/images/imagetest"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/platforms"
+	digest "github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestMessageSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(ocispec.Index{MediaType: ocispec.MediaTypeImageIndex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(b),
+		Size:      int64(len(b)),
+	}
+	if err := content.WriteBlob(ctx, cs, "test-index", bytes.NewReader(b), desc); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newDefaultConverter(nil, false, platforms.All, ConvertHooks{})
+	c.messageSizeLimit = 16
+
+	// An index descriptor claiming a size beyond the limit must be
+	// rejected before the blob is read.
+	oversized := desc
+	oversized.Size = 1 << 30
+	_, cerr := c.convert(ctx, cs, oversized)
+	if !errdefs.IsInvalidArgument(cerr) {
+		t.Errorf("expected invalid argument error for oversized index, got %v", cerr)
+	}
+	if cerr != nil && !strings.Contains(cerr.Error(), "limit") {
+		t.Errorf("unexpected error message: %v", cerr)
+	}
+
+	// Within the limit the same index converts without error.
+	c.messageSizeLimit = DefaultMessageSizeLimit
+	if _, err := c.convert(ctx, cs, desc); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWithConfigMediaType(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layerDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2LayerGzip, []byte("gzip layer bytes"))
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Config, configJSON)
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: images.MediaTypeDockerSchema2Manifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Manifest, manifestJSON)
+
+	client := &testClient{
+		cs: cs,
+		imgs: map[string]images.Image{
+			"src": {Name: "src", Target: manifestDesc},
+		},
+	}
+
+	dst, err := Convert(ctx, client, "dst", "src",
+		WithPlatform(platforms.All),
+		WithConfigMediaType(ocispec.MediaTypeImageConfig),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var manifest ocispec.Manifest
+	p, err := content.ReadBlob(ctx, cs, dst.Target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Config.MediaType != ocispec.MediaTypeImageConfig {
+		t.Errorf("unexpected config media type %q", manifest.Config.MediaType)
+	}
+	if manifest.Config.Digest != configDesc.Digest {
+		t.Error("config blob should not be rewritten")
+	}
+	if manifest.MediaType != images.MediaTypeDockerSchema2Manifest {
+		t.Errorf("manifest media type changed to %q", manifest.MediaType)
+	}
+	if manifest.Layers[0].MediaType != images.MediaTypeDockerSchema2LayerGzip {
+		t.Errorf("layer media type changed to %q", manifest.Layers[0].MediaType)
+	}
+}
+
+func TestWithConfigMediaTypeUnknown(t *testing.T) {
+	var copts convertOpts
+	if err := WithConfigMediaType("application/octet-stream")(&copts); err == nil {
+		t.Error("expected error for unknown config media type")
+	}
+}
+
+func TestWithDigestAlgorithm(t *testing.T) {
+	ctx := context.Background()
+	// A labeled store is needed to observe the rebuilt GC labels.
+	cs := imagetest.NewContentStore(ctx, t).Store
+
+	layerBytes := []byte("gzip layer bytes")
+	layerDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2LayerGzip, layerBytes)
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Config, configJSON)
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: images.MediaTypeDockerSchema2Manifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Manifest, manifestJSON)
+
+	client := &testClient{
+		cs: cs,
+		imgs: map[string]images.Image{
+			"src": {Name: "src", Target: manifestDesc},
+		},
+	}
+
+	dst, err := Convert(ctx, client, "dst", "src",
+		WithPlatform(platforms.All),
+		WithDigestAlgorithm(digest.SHA512),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if algo := dst.Target.Digest.Algorithm(); algo != digest.SHA512 {
+		t.Fatalf("expected sha512 target digest, got %s", algo)
+	}
+
+	var manifest ocispec.Manifest
+	p, err := content.ReadBlob(ctx, cs, dst.Target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if algo := manifest.Config.Digest.Algorithm(); algo != digest.SHA512 {
+		t.Errorf("expected sha512 config digest, got %s", algo)
+	}
+	if algo := manifest.Layers[0].Digest.Algorithm(); algo != digest.SHA512 {
+		t.Errorf("expected sha512 layer digest, got %s", algo)
+	}
+
+	// The blobs are unchanged, only re-addressed.
+	b, err := content.ReadBlob(ctx, cs, manifest.Layers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, layerBytes) {
+		t.Error("layer content changed during re-addressing")
+	}
+	if b, err = content.ReadBlob(ctx, cs, manifest.Config); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, configJSON) {
+		t.Error("config content changed during re-addressing")
+	}
+
+	// GC labels on the new manifest must reference the new digests.
+	info, err := cs.Info(ctx, dst.Target.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Labels["containerd.io/gc.ref.content.config"]; got != manifest.Config.Digest.String() {
+		t.Errorf("unexpected config GC label %q", got)
+	}
+	if got := info.Labels["containerd.io/gc.ref.content.l.0"]; got != manifest.Layers[0].Digest.String() {
+		t.Errorf("unexpected layer GC label %q", got)
+	}
+}
+
+func TestWithDigestAlgorithmUnavailable(t *testing.T) {
+	var copts convertOpts
+	if err := WithDigestAlgorithm(digest.Algorithm("md5"))(&copts); err == nil {
+		t.Error("expected error for unavailable digest algorithm")
+	}
+}
+
+func TestWithPreserveAnnotations(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layerDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2LayerGzip, []byte("gzip layer bytes"))
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Config, configJSON)
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: images.MediaTypeDockerSchema2Manifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Manifest, manifestJSON)
+	manifestDesc.Annotations = map[string]string{
+		"org.example.team": "runtime",
+		"org.example.ci":   "build-42",
+	}
+
+	client := &testClient{
+		cs: cs,
+		imgs: map[string]images.Image{
+			"src": {Name: "src", Target: manifestDesc},
+		},
+	}
+
+	// Without the option every annotation is dropped from the Docker
+	// manifest descriptor.
+	dst, err := Convert(ctx, client, "dst", "src", WithPlatform(platforms.All))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dst.Target.Annotations) != 0 {
+		t.Errorf("expected annotations to be dropped, got %v", dst.Target.Annotations)
+	}
+
+	// Listed keys survive; others are still dropped.
+	dst, err = Convert(ctx, client, "dst2", "src",
+		WithPlatform(platforms.All),
+		WithPreserveAnnotations("org.example.team"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dst.Target.Annotations["org.example.team"] != "runtime" {
+		t.Errorf("expected preserved annotation, got %v", dst.Target.Annotations)
+	}
+	if _, ok := dst.Target.Annotations["org.example.ci"]; ok {
+		t.Errorf("expected unlisted annotation to be dropped, got %v", dst.Target.Annotations)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/core/leases"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/platforms"
+	digest "github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// testClient is a minimal converter.Client over a local content store and an
+// in-memory image store, without lease support.
+type testClient struct {
+	cs   content.Store
+	imgs map[string]images.Image
+}
+
+func (c *testClient) WithLease(ctx context.Context, opts ...leases.Opt) (context.Context, func(context.Context) error, error) {
+	return ctx, func(context.Context) error { return nil }, nil
+}
+
+func (c *testClient) ContentStore() content.Store {
+	return c.cs
+}
+
+func (c *testClient) ImageService() images.Store {
+	return c
+}
+
+func (c *testClient) Get(ctx context.Context, name string) (images.Image, error) {
+	img, ok := c.imgs[name]
+	if !ok {
+		return images.Image{}, fmt.Errorf("image %q: %w", name, errdefs.ErrNotFound)
+	}
+	return img, nil
+}
+
+func (c *testClient) List(ctx context.Context, filters ...string) ([]images.Image, error) {
+	var list []images.Image
+	for _, img := range c.imgs {
+		list = append(list, img)
+	}
+	return list, nil
+}
+
+func (c *testClient) Create(ctx context.Context, image images.Image) (images.Image, error) {
+	c.imgs[image.Name] = image
+	return image, nil
+}
+
+func (c *testClient) Update(ctx context.Context, image images.Image, fieldpaths ...string) (images.Image, error) {
+	c.imgs[image.Name] = image
+	return image, nil
+}
+
+func (c *testClient) Delete(ctx context.Context, name string, opts ...images.DeleteOpt) error {
+	delete(c.imgs, name)
+	return nil
+}
+
+func TestWithDigestRecorder(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Docker schema 2 fixture: one gzip layer, config with a matching diff ID.
+	uncompressed := []byte("layer content to be recompressed as zstd")
+	diffID := digest.FromBytes(uncompressed)
+	var gzBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzBuf)
+	if _, err := gw.Write(uncompressed); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	layerDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2LayerGzip, gzBuf.Bytes())
+
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		RootFS: ocispec.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{diffID},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Config, configJSON)
+
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: images.MediaTypeDockerSchema2Manifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Manifest, manifestJSON)
+
+	client := &testClient{
+		cs: cs,
+		imgs: map[string]images.Image{
+			"src": {Name: "src", Target: manifestDesc},
+		},
+	}
+
+	var digestMap map[digest.Digest]digest.Digest
+	dst, err := Convert(ctx, client, "dst", "src",
+		WithLayerConvertFunc(ZstdLayerConvertFunc(3)),
+		WithDockerToOCI(true),
+		WithPlatform(platforms.All),
+		WithDigestRecorder(&digestMap),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The layer was recompressed and the manifest rewritten; both old digests
+	// must map to the digests referenced by the converted image.
+	newManifest, err := images.Manifest(ctx, cs, dst.Target, platforms.All)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := digestMap[manifestDesc.Digest]; !ok || got != dst.Target.Digest {
+		t.Errorf("expected manifest mapping %s -> %s, got %s (present %v)", manifestDesc.Digest, dst.Target.Digest, got, ok)
+	}
+	if got, ok := digestMap[layerDesc.Digest]; !ok || got != newManifest.Layers[0].Digest {
+		t.Errorf("expected layer mapping %s -> %s, got %s (present %v)", layerDesc.Digest, newManifest.Layers[0].Digest, got, ok)
+	}
+	for old, new := range digestMap {
+		if old == new {
+			t.Errorf("digest map contains unchanged digest %s", old)
+		}
+	}
+}
+
+func TestWithDigestRecorderNil(t *testing.T) {
+	var copts convertOpts
+	if err := WithDigestRecorder(nil)(&copts); err == nil {
+		t.Fatal("expected error for nil digest recorder map")
+	}
+}
+
+func TestRebuildGCLabels(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := writeBlob(ctx, t, cs, ocispec.MediaTypeImageConfig, []byte(`{"os":"linux"}`))
+	layer0 := writeBlob(ctx, t, cs, ocispec.MediaTypeImageLayerGzip, []byte("layer-0"))
+	layer1 := writeBlob(ctx, t, cs, ocispec.MediaTypeImageLayerGzip, []byte("layer-1"))
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layer0, layer1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := writeBlob(ctx, t, cs, ocispec.MediaTypeImageManifest, manifestJSON)
+
+	labels, err := RebuildGCLabels(ctx, cs, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"containerd.io/gc.ref.content.config": config.Digest.String(),
+		"containerd.io/gc.ref.content.l.0":    layer0.Digest.String(),
+		"containerd.io/gc.ref.content.l.1":    layer1.Digest.String(),
+	}
+	if len(labels) != len(expected) {
+		t.Fatalf("expected %d labels, got %v", len(expected), labels)
+	}
+	for k, v := range expected {
+		if labels[k] != v {
+			t.Errorf("expected %s=%s, got %s", k, v, labels[k])
+		}
+	}
+
+	indexJSON, err := json.Marshal(ocispec.Index{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{manifest},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := writeBlob(ctx, t, cs, ocispec.MediaTypeImageIndex, indexJSON)
+
+	labels, err = RebuildGCLabels(ctx, cs, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels["containerd.io/gc.ref.content.m.0"] != manifest.Digest.String() {
+		t.Errorf("expected index label to reference %s, got %v", manifest.Digest, labels)
+	}
+
+	if _, err := RebuildGCLabels(ctx, cs, layer0); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument for layer media type, got %v", err)
+	}
+}
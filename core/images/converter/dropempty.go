@@ -0,0 +1,211 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/pkg/archive/compression"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// IsEmptyLayer reports whether the layer blob contains no filesystem
+// entries, i.e. its uncompressed content is an empty tar archive.
+func IsEmptyLayer(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) (bool, error) {
+	ra, err := provider.ReaderAt(ctx, desc)
+	if err != nil {
+		return false, err
+	}
+	defer ra.Close()
+	sr := io.NewSectionReader(ra, 0, desc.Size)
+	r, err := compression.DecompressStream(sr)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	if _, err := tar.NewReader(r).Next(); err == io.EOF {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// DropEmptyLayers returns a hook which removes empty layers from converted
+// manifests together with the matching entries in the config, so the layer
+// list, the rootfs diff IDs and the non-empty history entries stay in step.
+// For each layer detected by IsEmptyLayer, its diff ID and the history entry
+// aligned with it are dropped in the same rewrite; history entries already
+// marked empty_layer carry no layer and are left untouched. Use it as a
+// PostConvertHook:
+//
+//	IndexConvertFuncWithHook(nil, true, platforms.All, ConvertHooks{
+//		PostConvertHook: DropEmptyLayers(),
+//	})
+func DropEmptyLayers() ConvertHookFunc {
+	return func(ctx context.Context, cs content.Store, orgDesc ocispec.Descriptor, newDesc *ocispec.Descriptor) (*ocispec.Descriptor, error) {
+		desc := orgDesc
+		if newDesc != nil {
+			desc = *newDesc
+		}
+		if !images.IsManifestType(desc.MediaType) {
+			return newDesc, nil
+		}
+
+		info, err := cs.Info(ctx, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		p, err := content.ReadBlob(ctx, cs, desc)
+		if err != nil {
+			return nil, err
+		}
+		var manifest ocispec.Manifest
+		if err := json.Unmarshal(p, &manifest); err != nil {
+			return nil, err
+		}
+
+		var (
+			layers  = make([]ocispec.Descriptor, 0, len(manifest.Layers))
+			removed = make(map[int]struct{})
+		)
+		for i, l := range manifest.Layers {
+			empty, err := IsEmptyLayer(ctx, cs, l)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check layer %s for emptiness: %w", l.Digest, err)
+			}
+			if empty {
+				removed[i] = struct{}{}
+				continue
+			}
+			layers = append(layers, l)
+		}
+		if len(removed) == 0 {
+			return newDesc, nil
+		}
+
+		newConfig, err := dropConfigEntries(ctx, cs, manifest.Config, len(manifest.Layers), removed)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Config = *newConfig
+		manifest.Layers = layers
+
+		labels := map[string]string{}
+		for k, v := range info.Labels {
+			if strings.HasPrefix(k, "containerd.io/gc.ref.content.l.") {
+				// Stale layer references are rebuilt below.
+				continue
+			}
+			labels[k] = v
+		}
+		labels["containerd.io/gc.ref.content.config"] = manifest.Config.Digest.String()
+		for i, l := range manifest.Layers {
+			labels[fmt.Sprintf("containerd.io/gc.ref.content.l.%d", i)] = l.Digest.String()
+		}
+		return writeJSON(ctx, cs, &manifest, desc, labels)
+	}
+}
+
+// dropConfigEntries rewrites the config to remove the rootfs diff IDs and
+// non-empty history entries aligned with the removed layer indexes,
+// preserving all other config fields. Either list is left untouched when its
+// length does not parallel the layer list, since the correspondence cannot
+// be established.
+func dropConfigEntries(ctx context.Context, cs content.Store, configDesc ocispec.Descriptor, layerCount int, removed map[int]struct{}) (*ocispec.Descriptor, error) {
+	info, err := cs.Info(ctx, configDesc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	b, err := content.ReadBlob(ctx, cs, configDesc)
+	if err != nil {
+		return nil, err
+	}
+	var cfg DualConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, err
+	}
+
+	if raw, ok := cfg["rootfs"]; ok {
+		var rootfs struct {
+			Type    string          `json:"type"`
+			DiffIDs []digest.Digest `json:"diff_ids"`
+		}
+		if err := json.Unmarshal(*raw, &rootfs); err != nil {
+			return nil, err
+		}
+		if len(rootfs.DiffIDs) == layerCount {
+			diffIDs := rootfs.DiffIDs[:0]
+			for i, d := range rootfs.DiffIDs {
+				if _, ok := removed[i]; !ok {
+					diffIDs = append(diffIDs, d)
+				}
+			}
+			rootfs.DiffIDs = diffIDs
+			rb, err := json.Marshal(rootfs)
+			if err != nil {
+				return nil, err
+			}
+			cfg["rootfs"] = (*json.RawMessage)(&rb)
+		}
+	}
+
+	if raw, ok := cfg["history"]; ok {
+		var history []ocispec.History
+		if err := json.Unmarshal(*raw, &history); err != nil {
+			return nil, err
+		}
+		nonEmpty := 0
+		for _, h := range history {
+			if !h.EmptyLayer {
+				nonEmpty++
+			}
+		}
+		if nonEmpty == layerCount {
+			var (
+				kept     = history[:0]
+				layerIdx int
+			)
+			for _, h := range history {
+				if !h.EmptyLayer {
+					if _, ok := removed[layerIdx]; ok {
+						layerIdx++
+						continue
+					}
+					layerIdx++
+				}
+				kept = append(kept, h)
+			}
+			hb, err := json.Marshal(kept)
+			if err != nil {
+				return nil, err
+			}
+			cfg["history"] = (*json.RawMessage)(&hb)
+		}
+	}
+
+	return writeJSON(ctx, cs, cfg, configDesc, info.Labels)
+}
@@ -0,0 +1,167 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// layerTar builds an uncompressed tar layer with the given files.
+func layerTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDropEmptyLayers(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := writeBlob(ctx, t, cs, ocispec.MediaTypeImageLayer, layerTar(t, map[string]string{"etc/hostname": "a\n"}))
+	empty := writeBlob(ctx, t, cs, ocispec.MediaTypeImageLayer, layerTar(t, nil))
+	last := writeBlob(ctx, t, cs, ocispec.MediaTypeImageLayer, layerTar(t, map[string]string{"etc/hosts": "b\n"}))
+
+	if ok, err := IsEmptyLayer(ctx, cs, empty); err != nil || !ok {
+		t.Fatalf("expected empty layer to be detected, got %v, %v", ok, err)
+	}
+	if ok, err := IsEmptyLayer(ctx, cs, first); err != nil || ok {
+		t.Fatalf("expected non-empty layer, got %v, %v", ok, err)
+	}
+
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		RootFS: ocispec.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{first.Digest, empty.Digest, last.Digest},
+		},
+		History: []ocispec.History{
+			{CreatedBy: "ENV app=1", EmptyLayer: true},
+			{CreatedBy: "ADD first /"},
+			{CreatedBy: "RUN true"},
+			{CreatedBy: "ADD last /"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDesc := writeBlob(ctx, t, cs, ocispec.MediaTypeImageConfig, configJSON)
+
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{first, empty, last},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := writeBlob(ctx, t, cs, ocispec.MediaTypeImageManifest, manifestJSON)
+
+	hook := DropEmptyLayers()
+	newDesc, err := hook(ctx, cs, manifestDesc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newDesc == nil || newDesc.Digest == manifestDesc.Digest {
+		t.Fatal("expected manifest to be rewritten")
+	}
+
+	p, err := content.ReadBlob(ctx, cs, *newDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Layers) != 2 {
+		t.Fatalf("expected 2 layers, got %d", len(manifest.Layers))
+	}
+	if manifest.Layers[0].Digest != first.Digest || manifest.Layers[1].Digest != last.Digest {
+		t.Errorf("unexpected layers %v", manifest.Layers)
+	}
+
+	p, err = content.ReadBlob(ctx, cs, manifest.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config ocispec.Image
+	if err := json.Unmarshal(p, &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.RootFS.DiffIDs) != len(manifest.Layers) {
+		t.Fatalf("expected %d diff IDs, got %d", len(manifest.Layers), len(config.RootFS.DiffIDs))
+	}
+	if config.RootFS.DiffIDs[0] != first.Digest || config.RootFS.DiffIDs[1] != last.Digest {
+		t.Errorf("unexpected diff IDs %v", config.RootFS.DiffIDs)
+	}
+
+	// The non-empty history entries must stay aligned with the layer list,
+	// and entries marked empty_layer are preserved.
+	var nonEmpty []string
+	for _, h := range config.History {
+		if !h.EmptyLayer {
+			nonEmpty = append(nonEmpty, h.CreatedBy)
+		}
+	}
+	if len(nonEmpty) != len(manifest.Layers) {
+		t.Fatalf("expected %d non-empty history entries, got %d", len(manifest.Layers), len(nonEmpty))
+	}
+	if nonEmpty[0] != "ADD first /" || nonEmpty[1] != "ADD last /" {
+		t.Errorf("unexpected history entries %v", nonEmpty)
+	}
+	if len(config.History) != 3 || !config.History[0].EmptyLayer {
+		t.Errorf("expected the empty_layer history entry to be preserved, got %v", config.History)
+	}
+
+	// A manifest without empty layers is left untouched.
+	unchanged, err := hook(ctx, cs, *newDesc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unchanged != nil && unchanged.Digest != newDesc.Digest {
+		t.Errorf("expected no rewrite without empty layers, got %s", unchanged.Digest)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// dryRunStore keeps writes in an in-memory overlay instead of persisting
+// them, while still serving them back for read-after-write paths such as
+// diff ID computation. Reads fall through to the wrapped store.
+type dryRunStore struct {
+	content.Store
+
+	mu    sync.Mutex
+	blobs map[digest.Digest][]byte
+	infos map[digest.Digest]content.Info
+}
+
+func newDryRunStore(cs content.Store) *dryRunStore {
+	return &dryRunStore{
+		Store: cs,
+		blobs: map[digest.Digest][]byte{},
+		infos: map[digest.Digest]content.Info{},
+	}
+}
+
+func (s *dryRunStore) Writer(ctx context.Context, opts ...content.WriterOpt) (content.Writer, error) {
+	var wOpts content.WriterOpts
+	for _, opt := range opts {
+		if err := opt(&wOpts); err != nil {
+			return nil, err
+		}
+	}
+	return &dryRunWriter{
+		store:    s,
+		ref:      wOpts.Ref,
+		expected: wOpts.Desc.Digest,
+		start:    time.Now(),
+	}, nil
+}
+
+func (s *dryRunStore) Info(ctx context.Context, dgst digest.Digest) (content.Info, error) {
+	s.mu.Lock()
+	info, ok := s.infos[dgst]
+	s.mu.Unlock()
+	if ok {
+		return info, nil
+	}
+	return s.Store.Info(ctx, dgst)
+}
+
+func (s *dryRunStore) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (content.ReaderAt, error) {
+	s.mu.Lock()
+	b, ok := s.blobs[desc.Digest]
+	s.mu.Unlock()
+	if ok {
+		return nopCloserAt{bytes.NewReader(b)}, nil
+	}
+	return s.Store.ReaderAt(ctx, desc)
+}
+
+type nopCloserAt struct {
+	*bytes.Reader
+}
+
+func (nopCloserAt) Close() error { return nil }
+
+// dryRunWriter accumulates written bytes and commits them to the overlay.
+type dryRunWriter struct {
+	store    *dryRunStore
+	ref      string
+	expected digest.Digest
+	buf      bytes.Buffer
+	start    time.Time
+
+	committed bool
+	dgst      digest.Digest
+}
+
+func (w *dryRunWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *dryRunWriter) Close() error { return nil }
+
+// algorithm returns the digest algorithm the caller expects to commit
+// under, so non-canonical algorithms such as sha512 verify correctly.
+func (w *dryRunWriter) algorithm(expected digest.Digest) digest.Algorithm {
+	if expected == "" {
+		expected = w.expected
+	}
+	if expected != "" {
+		if alg := expected.Algorithm(); alg.Available() {
+			return alg
+		}
+	}
+	return digest.Canonical
+}
+
+func (w *dryRunWriter) Digest() digest.Digest {
+	if w.committed {
+		return w.dgst
+	}
+	return w.algorithm("").FromBytes(w.buf.Bytes())
+}
+
+func (w *dryRunWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	b := w.buf.Bytes()
+	if size > 0 && size != int64(len(b)) {
+		return fmt.Errorf("unexpected commit size %d, expected %d: %w", len(b), size, errdefs.ErrFailedPrecondition)
+	}
+	dgst := w.algorithm(expected).FromBytes(b)
+	if expected != "" && expected != dgst {
+		return fmt.Errorf("unexpected commit digest %s, expected %s: %w", dgst, expected, errdefs.ErrFailedPrecondition)
+	}
+
+	info := content.Info{
+		Digest:    dgst,
+		Size:      int64(len(b)),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	for _, opt := range opts {
+		if err := opt(&info); err != nil {
+			return err
+		}
+	}
+
+	w.store.mu.Lock()
+	w.store.blobs[dgst] = append([]byte{}, b...)
+	w.store.infos[dgst] = info
+	w.store.mu.Unlock()
+
+	w.committed = true
+	w.dgst = dgst
+	return nil
+}
+
+func (w *dryRunWriter) Status() (content.Status, error) {
+	return content.Status{
+		Ref:       w.ref,
+		Offset:    int64(w.buf.Len()),
+		Total:     int64(w.buf.Len()),
+		StartedAt: w.start,
+	}, nil
+}
+
+func (w *dryRunWriter) Truncate(size int64) error {
+	if size != 0 {
+		return fmt.Errorf("cannot truncate dry-run writer to %d: %w", size, errdefs.ErrNotImplemented)
+	}
+	w.buf.Reset()
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/containerd/platforms"
+	digest "github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func countBlobs(ctx context.Context, t *testing.T, cs content.Store) int {
+	t.Helper()
+	var n int
+	if err := cs.Walk(ctx, func(content.Info) error {
+		n++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestWithDryRun(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uncompressed := []byte("layer content to be recompressed as zstd")
+	diffID := digest.FromBytes(uncompressed)
+	var gzBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzBuf)
+	if _, err := gw.Write(uncompressed); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	layerDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2LayerGzip, gzBuf.Bytes())
+
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		RootFS: ocispec.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{diffID},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Config, configJSON)
+
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: images.MediaTypeDockerSchema2Manifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Manifest, manifestJSON)
+
+	client := &testClient{
+		cs: cs,
+		imgs: map[string]images.Image{
+			"src": {Name: "src", Target: manifestDesc},
+		},
+	}
+	blobsBefore := countBlobs(ctx, t, cs)
+
+	opts := []Opt{
+		WithLayerConvertFunc(ZstdLayerConvertFunc(3)),
+		WithDockerToOCI(true),
+		WithPlatform(platforms.All),
+	}
+	dry, err := Convert(ctx, client, "dst", "src", append(opts, WithDryRun())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dry.Target.Digest == manifestDesc.Digest {
+		t.Error("expected dry-run target to differ from the source for a rewriting conversion")
+	}
+	if got := countBlobs(ctx, t, cs); got != blobsBefore {
+		t.Errorf("expected no blobs written during dry run, got %d new", got-blobsBefore)
+	}
+	if _, ok := client.imgs["dst"]; ok {
+		t.Error("expected no destination image to be created during dry run")
+	}
+
+	// A real conversion must land on the digest the dry run predicted.
+	real, err := Convert(ctx, client, "dst", "src", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if real.Target.Digest != dry.Target.Digest {
+		t.Errorf("expected dry-run digest %s to match real conversion %s", dry.Target.Digest, real.Target.Digest)
+	}
+}
+
+func TestWithDryRunDigestAlgorithm(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layerDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2LayerGzip, []byte("gzip layer bytes"))
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Config, configJSON)
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: images.MediaTypeDockerSchema2Manifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Manifest, manifestJSON)
+
+	client := &testClient{
+		cs: cs,
+		imgs: map[string]images.Image{
+			"src": {Name: "src", Target: manifestDesc},
+		},
+	}
+	blobsBefore := countBlobs(ctx, t, cs)
+
+	// The dry-run overlay must verify commits with the requested
+	// algorithm, not the canonical one.
+	dry, err := Convert(ctx, client, "dst", "src",
+		WithPlatform(platforms.All),
+		WithDigestAlgorithm(digest.SHA512),
+		WithDryRun(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if algo := dry.Target.Digest.Algorithm(); algo != digest.SHA512 {
+		t.Errorf("expected sha512 target digest, got %s", algo)
+	}
+	if got := countBlobs(ctx, t, cs); got != blobsBefore {
+		t.Errorf("expected no blobs written during dry run, got %d new", got-blobsBefore)
+	}
+	if _, ok := client.imgs["dst"]; ok {
+		t.Error("expected no destination image to be created during dry run")
+	}
+}
@@ -0,0 +1,74 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// NormalizeManifest returns a hook which rewrites converted manifests into a
+// canonical JSON encoding: fields in the fixed order of the OCI manifest
+// struct and annotation keys sorted, so repeated conversions of the same
+// content produce bit-identical manifests. Layer order is semantic and is
+// never changed. Use it as a PostConvertHook:
+//
+//	IndexConvertFuncWithHook(nil, true, platforms.All, ConvertHooks{
+//		PostConvertHook: NormalizeManifest(),
+//	})
+func NormalizeManifest() ConvertHookFunc {
+	return func(ctx context.Context, cs content.Store, orgDesc ocispec.Descriptor, newDesc *ocispec.Descriptor) (*ocispec.Descriptor, error) {
+		desc := orgDesc
+		if newDesc != nil {
+			desc = *newDesc
+		}
+		if !images.IsManifestType(desc.MediaType) {
+			return newDesc, nil
+		}
+
+		info, err := cs.Info(ctx, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		p, err := content.ReadBlob(ctx, cs, desc)
+		if err != nil {
+			return nil, err
+		}
+
+		// Round-tripping through the fixed struct yields the canonical
+		// encoding; map keys (annotations) are sorted by encoding/json.
+		var manifest ocispec.Manifest
+		if err := json.Unmarshal(p, &manifest); err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(&manifest)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(p, b) {
+			// Already canonical.
+			return newDesc, nil
+		}
+
+		return writeJSON(ctx, cs, &manifest, desc, info.Labels)
+	}
+}
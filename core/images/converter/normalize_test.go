@@ -0,0 +1,75 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/containerd/platforms"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestNormalizeManifest(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := writeBlob(ctx, t, cs, ocispec.MediaTypeImageConfig, []byte(`{}`))
+	layer := writeBlob(ctx, t, cs, ocispec.MediaTypeImageLayer, []byte("layer"))
+
+	// Hand-written JSON with unordered fields and annotation keys; the
+	// hook must rewrite it into the canonical struct encoding.
+	raw := []byte(`{"layers":[{"mediaType":"` + layer.MediaType + `","digest":"` + layer.Digest.String() + `","size":` +
+		itoa(layer.Size) + `}],"annotations":{"z.example":"1","a.example":"2"},"config":{"mediaType":"` +
+		config.MediaType + `","digest":"` + config.Digest.String() + `","size":` + itoa(config.Size) +
+		`},"schemaVersion":2,"mediaType":"` + ocispec.MediaTypeImageManifest + `"}`)
+	desc := writeBlob(ctx, t, cs, ocispec.MediaTypeImageManifest, raw)
+
+	hook := NormalizeManifest()
+	first, err := hook(ctx, cs, desc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == nil {
+		t.Fatal("expected non-canonical manifest to be rewritten")
+	}
+	if first.Digest == desc.Digest {
+		t.Error("expected digest to change for non-canonical input")
+	}
+
+	// Running the full convert func over the normalized manifest again must
+	// be a no-op, leaving the digest unchanged.
+	fn := IndexConvertFuncWithHook(nil, false, platforms.All, ConvertHooks{
+		PostConvertHook: NormalizeManifest(),
+	})
+	second, err := fn(ctx, cs, *first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != nil && second.Digest != first.Digest {
+		t.Errorf("expected identical digest on repeated conversion, got %s and %s", first.Digest, second.Digest)
+	}
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
@@ -0,0 +1,76 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/pkg/archive/compression"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// AnnotationUncompressedSize is the annotation key used to record the
+// uncompressed byte count of a layer blob.
+const AnnotationUncompressedSize = "containerd.io/uncompressed-size"
+
+// AnnotateUncompressedSize returns a ConvertFunc which annotates each layer
+// descriptor with its uncompressed size under AnnotationUncompressedSize.
+// The layer blob itself is not modified; compressed layers are decompressed
+// only to measure the byte count, while already-uncompressed layers reuse
+// the descriptor size.
+func AnnotateUncompressedSize() ConvertFunc {
+	return func(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (*ocispec.Descriptor, error) {
+		if !images.IsLayerType(desc.MediaType) {
+			// No conversion. No need to return an error here.
+			return nil, nil
+		}
+		if _, ok := desc.Annotations[AnnotationUncompressedSize]; ok {
+			return nil, nil
+		}
+
+		size := desc.Size
+		ra, err := cs.ReaderAt(ctx, desc)
+		if err != nil {
+			return nil, err
+		}
+		defer ra.Close()
+		sr := io.NewSectionReader(ra, 0, desc.Size)
+		dr, err := compression.DecompressStream(sr)
+		if err != nil {
+			return nil, err
+		}
+		defer dr.Close()
+		if dr.GetCompression() != compression.Uncompressed {
+			size, err = io.Copy(io.Discard, dr)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		newDesc := desc
+		newDesc.Annotations = make(map[string]string, len(desc.Annotations)+1)
+		for k, v := range desc.Annotations {
+			newDesc.Annotations[k] = v
+		}
+		newDesc.Annotations[AnnotationUncompressedSize] = strconv.FormatInt(size, 10)
+		return &newDesc, nil
+	}
+}
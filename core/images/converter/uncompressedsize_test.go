@@ -0,0 +1,106 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func writeBlob(ctx context.Context, t *testing.T, cs content.Store, mediaType string, b []byte) ocispec.Descriptor {
+	t.Helper()
+	dgst := digest.FromBytes(b)
+	if err := content.WriteBlob(ctx, cs, "test-"+dgst.String(), bytes.NewReader(b), ocispec.Descriptor{Digest: dgst, Size: int64(len(b))}); err != nil {
+		t.Fatal(err)
+	}
+	return ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    dgst,
+		Size:      int64(len(b)),
+	}
+}
+
+func TestAnnotateUncompressedSize(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uncompressed := []byte("uncompressed layer content for size annotation")
+	var gzBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzBuf)
+	if _, err := gw.Write(uncompressed); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fn := AnnotateUncompressedSize()
+
+	t.Run("GzipLayer", func(t *testing.T) {
+		desc := writeBlob(ctx, t, cs, ocispec.MediaTypeImageLayerGzip, gzBuf.Bytes())
+		newDesc, err := fn(ctx, cs, desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newDesc == nil {
+			t.Fatal("expected converted descriptor")
+		}
+		if newDesc.Digest != desc.Digest || newDesc.Size != desc.Size {
+			t.Error("blob must not be modified")
+		}
+		expected := strconv.Itoa(len(uncompressed))
+		if v := newDesc.Annotations[AnnotationUncompressedSize]; v != expected {
+			t.Errorf("unexpected annotation %q, expected %q", v, expected)
+		}
+	})
+
+	t.Run("UncompressedLayer", func(t *testing.T) {
+		desc := writeBlob(ctx, t, cs, ocispec.MediaTypeImageLayer, uncompressed)
+		newDesc, err := fn(ctx, cs, desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newDesc == nil {
+			t.Fatal("expected converted descriptor")
+		}
+		if v := newDesc.Annotations[AnnotationUncompressedSize]; v != strconv.Itoa(len(uncompressed)) {
+			t.Errorf("unexpected annotation %q", v)
+		}
+	})
+
+	t.Run("NonLayerSkipped", func(t *testing.T) {
+		desc := writeBlob(ctx, t, cs, ocispec.MediaTypeImageConfig, []byte("{}"))
+		newDesc, err := fn(ctx, cs, desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newDesc != nil {
+			t.Error("expected no conversion for non-layer blob")
+		}
+	})
+}
@@ -0,0 +1,72 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/errdefs"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// RegistryConstraints captures the limits a registry imposes on pushed
+// images, checked before a push rather than discovered as a rejection
+// halfway through one.
+type RegistryConstraints struct {
+	// AllowedMediaTypes restricts every descriptor of the image to the
+	// listed media types. Empty means any media type is accepted.
+	AllowedMediaTypes []string
+
+	// MaxManifestSize caps the size in bytes of manifest and index blobs.
+	// Zero means unlimited.
+	MaxManifestSize int64
+
+	// AllowForeignLayers permits non-distributable (foreign) layers, which
+	// most registries reject on push.
+	AllowForeignLayers bool
+}
+
+// ValidateForRegistry walks the image at desc and returns the first
+// descriptor violating the given constraints, wrapped as a failed
+// precondition. A nil return means every reachable descriptor complies.
+func ValidateForRegistry(ctx context.Context, cs content.Store, desc ocispec.Descriptor, constraints RegistryConstraints) error {
+	var allowed map[string]struct{}
+	if len(constraints.AllowedMediaTypes) > 0 {
+		allowed = make(map[string]struct{}, len(constraints.AllowedMediaTypes))
+		for _, mt := range constraints.AllowedMediaTypes {
+			allowed[mt] = struct{}{}
+		}
+	}
+	handler := images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if allowed != nil {
+			if _, ok := allowed[desc.MediaType]; !ok {
+				return nil, fmt.Errorf("media type %s of %s not accepted by registry: %w", desc.MediaType, desc.Digest, errdefs.ErrFailedPrecondition)
+			}
+		}
+		if constraints.MaxManifestSize > 0 && (images.IsManifestType(desc.MediaType) || images.IsIndexType(desc.MediaType)) && desc.Size > constraints.MaxManifestSize {
+			return nil, fmt.Errorf("manifest %s is %d bytes, exceeding the registry cap of %d: %w", desc.Digest, desc.Size, constraints.MaxManifestSize, errdefs.ErrFailedPrecondition)
+		}
+		if !constraints.AllowForeignLayers && images.IsNonDistributable(desc.MediaType) {
+			return nil, fmt.Errorf("foreign layer %s cannot be pushed to registry: %w", desc.Digest, errdefs.ErrFailedPrecondition)
+		}
+		return images.Children(ctx, cs, desc)
+	})
+	return images.Walk(ctx, handler, desc)
+}
@@ -0,0 +1,127 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/containerd/errdefs"
+	ocispecs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestValidateForRegistry(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDesc := writeBlob(ctx, t, cs, ocispec.MediaTypeImageConfig, configJSON)
+	layerDesc := writeBlob(ctx, t, cs, ocispec.MediaTypeImageLayerGzip, []byte("layer bytes"))
+	foreignDesc := writeBlob(ctx, t, cs, ocispec.MediaTypeImageLayerNonDistributableGzip, []byte("foreign bytes")) //nolint:staticcheck // deprecated
+
+	newManifest := func(layers ...ocispec.Descriptor) ocispec.Descriptor {
+		b, err := json.Marshal(ocispec.Manifest{
+			Versioned: ocispecs.Versioned{SchemaVersion: 2},
+			MediaType: ocispec.MediaTypeImageManifest,
+			Config:    configDesc,
+			Layers:    layers,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writeBlob(ctx, t, cs, ocispec.MediaTypeImageManifest, b)
+	}
+
+	ociOnly := []string{
+		ocispec.MediaTypeImageManifest,
+		ocispec.MediaTypeImageConfig,
+		ocispec.MediaTypeImageLayerGzip,
+	}
+
+	manifest := newManifest(layerDesc)
+	if err := ValidateForRegistry(ctx, cs, manifest, RegistryConstraints{
+		AllowedMediaTypes: ociOnly,
+		MaxManifestSize:   4 * 1024 * 1024,
+	}); err != nil {
+		t.Errorf("expected compliant image to validate, got %v", err)
+	}
+
+	// Disallowed media type.
+	err = ValidateForRegistry(ctx, cs, manifest, RegistryConstraints{
+		AllowedMediaTypes: []string{ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageConfig},
+	})
+	if !errdefs.IsFailedPrecondition(err) || !strings.Contains(err.Error(), ocispec.MediaTypeImageLayerGzip) {
+		t.Errorf("expected media type violation, got %v", err)
+	}
+
+	// Manifest over the size cap.
+	err = ValidateForRegistry(ctx, cs, manifest, RegistryConstraints{MaxManifestSize: 16})
+	if !errdefs.IsFailedPrecondition(err) || !strings.Contains(err.Error(), "exceeding") {
+		t.Errorf("expected manifest size violation, got %v", err)
+	}
+
+	// Foreign layers are rejected unless explicitly permitted.
+	foreign := newManifest(foreignDesc)
+	err = ValidateForRegistry(ctx, cs, foreign, RegistryConstraints{})
+	if !errdefs.IsFailedPrecondition(err) || !strings.Contains(err.Error(), "foreign layer") {
+		t.Errorf("expected foreign layer violation, got %v", err)
+	}
+	if err := ValidateForRegistry(ctx, cs, foreign, RegistryConstraints{AllowForeignLayers: true}); err != nil {
+		t.Errorf("expected foreign layer to be permitted, got %v", err)
+	}
+
+	// The layer check also applies under an index.
+	indexJSON, err := json.Marshal(ocispec.Index{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{foreign},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexDesc := writeBlob(ctx, t, cs, ocispec.MediaTypeImageIndex, indexJSON)
+	if err := ValidateForRegistry(ctx, cs, indexDesc, RegistryConstraints{}); !errdefs.IsFailedPrecondition(err) {
+		t.Errorf("expected foreign layer violation through index, got %v", err)
+	}
+}
+
+func TestValidateForRegistryUnknownMediaType(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Manifest, []byte(`{"schemaVersion":2}`))
+	if err := ValidateForRegistry(ctx, cs, desc, RegistryConstraints{
+		AllowedMediaTypes: []string{ocispec.MediaTypeImageManifest},
+	}); !errdefs.IsFailedPrecondition(err) {
+		t.Errorf("expected docker manifest to be rejected, got %v", err)
+	}
+}
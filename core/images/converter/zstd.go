@@ -0,0 +1,153 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/pkg/archive/compression"
+	"github.com/containerd/containerd/v2/pkg/labels"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/platforms"
+	"github.com/klauspost/compress/zstd"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// DockerToOCIZstdConvertFunc returns an index convert func that converts a
+// Docker schema 2 image to OCI format and recompresses its layers with zstd
+// at the given level in a single pass. Layer diff IDs are unchanged since
+// only the compression changes, and GC labels are carried over, so this is
+// the recommended entry point over composing WithDockerToOCI with a custom
+// layer convert func.
+func DockerToOCIZstdConvertFunc(level int, platformMC platforms.MatchComparer) ConvertFunc {
+	return IndexConvertFuncWithHook(ZstdLayerConvertFunc(level), true, platformMC, ConvertHooks{})
+}
+
+// ZstdLayerConvertFunc converts layers into zstd compressed layers with the
+// given compression level. Layers which are already zstd compressed are left
+// untouched.
+func ZstdLayerConvertFunc(level int) ConvertFunc {
+	return func(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (*ocispec.Descriptor, error) {
+		if !images.IsLayerType(desc.MediaType) || isZstdType(desc.MediaType) {
+			// No conversion. No need to return an error here.
+			return nil, nil
+		}
+		info, err := cs.Info(ctx, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		readerAt, err := cs.ReaderAt(ctx, desc)
+		if err != nil {
+			return nil, err
+		}
+		defer readerAt.Close()
+		sr := io.NewSectionReader(readerAt, 0, desc.Size)
+		newR, err := compression.DecompressStream(sr)
+		if err != nil {
+			return nil, err
+		}
+		defer newR.Close()
+		ref := fmt.Sprintf("convert-zstd-from-%s", desc.Digest)
+		w, err := content.OpenWriter(ctx, cs, content.WithRef(ref))
+		if err != nil {
+			return nil, err
+		}
+		defer w.Close()
+
+		// Reset the writing position
+		// Old writer possibly remains without aborted
+		// (e.g. conversion interrupted by a signal)
+		if err := w.Truncate(0); err != nil {
+			return nil, err
+		}
+
+		cw := &countingWriter{w: w}
+		enc, err := zstd.NewWriter(cw, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		if err != nil {
+			return nil, err
+		}
+		// The diff ID is the digest of the uncompressed tar, which the
+		// compression change does not alter.
+		diffID := digest.Canonical.Digester()
+		if _, err := io.Copy(enc, io.TeeReader(newR, diffID.Hash())); err != nil {
+			enc.Close()
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		if err := newR.Close(); err != nil {
+			return nil, err
+		}
+
+		labelsMap := info.Labels
+		if labelsMap == nil {
+			labelsMap = make(map[string]string)
+		}
+		labelsMap[labels.LabelUncompressed] = diffID.Digest().String()
+		if err = w.Commit(ctx, 0, "", content.WithLabels(labelsMap)); err != nil && !errdefs.IsAlreadyExists(err) {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		newDesc := desc
+		newDesc.Digest = w.Digest()
+		newDesc.Size = cw.n
+		newDesc.MediaType = convertToZstdMediaType(newDesc.MediaType)
+		return &newDesc, nil
+	}
+}
+
+func isZstdType(mt string) bool {
+	switch mt {
+	case images.MediaTypeDockerSchema2LayerZstd,
+		ocispec.MediaTypeImageLayerZstd,
+		ocispec.MediaTypeImageLayerNonDistributableZstd: //nolint:staticcheck // deprecated
+		return true
+	default:
+		return false
+	}
+}
+
+func convertToZstdMediaType(mt string) string {
+	switch mt {
+	case images.MediaTypeDockerSchema2LayerForeign,
+		images.MediaTypeDockerSchema2LayerForeignGzip,
+		ocispec.MediaTypeImageLayerNonDistributable,     //nolint:staticcheck // deprecated
+		ocispec.MediaTypeImageLayerNonDistributableGzip: //nolint:staticcheck // deprecated
+		return ocispec.MediaTypeImageLayerNonDistributableZstd //nolint:staticcheck // deprecated
+	default:
+		return ocispec.MediaTypeImageLayerZstd
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
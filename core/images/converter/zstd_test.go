@@ -0,0 +1,150 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package converter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/pkg/archive/compression"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/containerd/platforms"
+	digest "github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestDockerToOCIZstdConvertFunc(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Docker schema 2 fixture: one gzip layer, config with a matching diff ID.
+	uncompressed := []byte("layer content destined for zstd recompression")
+	diffID := digest.FromBytes(uncompressed)
+	var gzBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzBuf)
+	if _, err := gw.Write(uncompressed); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	layerDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2LayerGzip, gzBuf.Bytes())
+
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		RootFS: ocispec.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{diffID},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Config, configJSON)
+
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: images.MediaTypeDockerSchema2Manifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := writeBlob(ctx, t, cs, images.MediaTypeDockerSchema2Manifest, manifestJSON)
+
+	fn := DockerToOCIZstdConvertFunc(3, platforms.All)
+	newDesc, err := fn(ctx, cs, manifestDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newDesc == nil {
+		t.Fatal("expected converted manifest descriptor")
+	}
+	if newDesc.MediaType != ocispec.MediaTypeImageManifest {
+		t.Errorf("unexpected manifest media type %q", newDesc.MediaType)
+	}
+
+	p, err := content.ReadBlob(ctx, cs, *newDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Config.MediaType != ocispec.MediaTypeImageConfig {
+		t.Errorf("unexpected config media type %q", manifest.Config.MediaType)
+	}
+	if len(manifest.Layers) != 1 {
+		t.Fatalf("expected 1 layer, got %d", len(manifest.Layers))
+	}
+	newLayer := manifest.Layers[0]
+	if newLayer.MediaType != ocispec.MediaTypeImageLayerZstd {
+		t.Errorf("unexpected layer media type %q", newLayer.MediaType)
+	}
+
+	// Recompression must not change the diff ID, so the config's rootfs
+	// must be untouched.
+	cp, err := content.ReadBlob(ctx, cs, manifest.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config ocispec.Image
+	if err := json.Unmarshal(cp, &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.RootFS.DiffIDs) != 1 || config.RootFS.DiffIDs[0] != diffID {
+		t.Errorf("unexpected diff IDs %v, expected [%s]", config.RootFS.DiffIDs, diffID)
+	}
+
+	// The zstd layer must decompress back to the original content.
+	ra, err := cs.ReaderAt(ctx, newLayer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ra.Close()
+	dr, err := compression.DecompressStream(io.NewSectionReader(ra, 0, newLayer.Size))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dr.Close()
+	b, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, uncompressed) {
+		t.Error("decompressed layer does not match original content")
+	}
+
+	// Diff ID lookups on the new blob must resolve to the original diff ID.
+	if got, err := images.GetDiffID(ctx, cs, newLayer); err != nil {
+		t.Fatal(err)
+	} else if got != diffID {
+		t.Errorf("unexpected diff ID %s, expected %s", got, diffID)
+	}
+}
@@ -21,10 +21,12 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"sync"
 
 	"github.com/containerd/containerd/v2/core/content"
 	"github.com/containerd/errdefs"
 	"github.com/containerd/platforms"
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
@@ -44,6 +46,15 @@ var (
 	// ErrEmptyWalk is used when the WalkNotEmpty handlers return no
 	// children (e.g.: they were filtered out).
 	ErrEmptyWalk = errors.New("image might be filtered out")
+
+	// ErrManifestCycle is returned by WalkChildren when a descriptor
+	// references one of its ancestors, which would otherwise cause an
+	// infinite walk.
+	ErrManifestCycle = errors.New("manifest tree contains a cycle")
+
+	// ErrMaxDepthExceeded is returned by DepthLimitHandler when a
+	// descriptor is reached beyond the configured depth.
+	ErrMaxDepthExceeded = errors.New("maximum descriptor depth exceeded")
 )
 
 // Handler handles image manifests
@@ -106,6 +117,128 @@ func Walk(ctx context.Context, handler Handler, descs ...ocispec.Descriptor) err
 	return nil
 }
 
+// WalkChildren recursively walks the children of desc resolved through
+// provider, tracking the digests on the current path in visited to detect
+// reference cycles. A crafted index which references itself or an ancestor
+// returns ErrManifestCycle instead of recursing forever. A nil visited map
+// may be passed and is allocated automatically.
+func WalkChildren(ctx context.Context, provider content.Provider, desc ocispec.Descriptor, visited map[digest.Digest]bool) error {
+	if visited == nil {
+		visited = map[digest.Digest]bool{}
+	}
+	if visited[desc.Digest] {
+		return fmt.Errorf("%s references an ancestor: %w", desc.Digest, ErrManifestCycle)
+	}
+	visited[desc.Digest] = true
+	defer delete(visited, desc.Digest)
+
+	children, err := Children(ctx, provider, desc)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := WalkChildren(ctx, provider, child, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkReadLimit caps the size of manifest and index blobs read by walk
+// helpers, guarding against a crafted image exhausting memory.
+const walkReadLimit = 8 * 1024 * 1024 // 8 MiB
+
+// CollectAnnotations walks the manifest tree rooted at desc via Children and
+// returns the annotations carried by each reachable descriptor, keyed by
+// digest. Descriptors without annotations are omitted from the result. This
+// is intended for policy checks which need to inspect every annotation in an
+// image without materializing the full tree.
+func CollectAnnotations(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) (map[digest.Digest]map[string]string, error) {
+	annotations := map[digest.Digest]map[string]string{}
+	handler := HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if len(desc.Annotations) > 0 {
+			m := make(map[string]string, len(desc.Annotations))
+			for k, v := range desc.Annotations {
+				m[k] = v
+			}
+			annotations[desc.Digest] = m
+		}
+		if !IsManifestType(desc.MediaType) && !IsIndexType(desc.MediaType) {
+			return nil, nil
+		}
+		if desc.Size > walkReadLimit {
+			return nil, fmt.Errorf("%s exceeds walk read limit of %d bytes: %w", desc.Digest, walkReadLimit, errdefs.ErrInvalidArgument)
+		}
+		return Children(ctx, provider, desc)
+	})
+	if err := Walk(ctx, handler, desc); err != nil {
+		return nil, err
+	}
+	return annotations, nil
+}
+
+// ReachableBlobs walks the provided descriptors via Children and returns the
+// set of all reachable blob digests. Shared content such as a common base
+// layer is deduplicated, and content reachable more than once (including via
+// a cycle) is not walked again. Callers can diff the result against the
+// content store's Walk to find dangling blobs.
+func ReachableBlobs(ctx context.Context, provider content.Provider, descs []ocispec.Descriptor) (map[digest.Digest]struct{}, error) {
+	reachable := map[digest.Digest]struct{}{}
+	handler := HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if _, ok := reachable[desc.Digest]; ok {
+			// Already seen, also breaks reference cycles.
+			return nil, ErrSkipDesc
+		}
+		reachable[desc.Digest] = struct{}{}
+		if !IsManifestType(desc.MediaType) && !IsIndexType(desc.MediaType) {
+			return nil, nil
+		}
+		if desc.Size > walkReadLimit {
+			return nil, fmt.Errorf("%s exceeds walk read limit of %d bytes: %w", desc.Digest, walkReadLimit, errdefs.ErrInvalidArgument)
+		}
+		return Children(ctx, provider, desc)
+	})
+	if err := Walk(ctx, handler, descs...); err != nil {
+		return nil, err
+	}
+	return reachable, nil
+}
+
+// Flatten walks the tree rooted at desc breadth-first via Children and
+// returns every reachable descriptor, starting with desc itself, in a flat
+// list: index, then manifests, then configs and layers. Descriptors are
+// deduplicated by digest in first-seen order, which also bounds walks over
+// crafted images with reference cycles.
+func Flatten(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	var (
+		flat  []ocispec.Descriptor
+		seen  = map[digest.Digest]struct{}{}
+		queue = []ocispec.Descriptor{desc}
+	)
+	for len(queue) > 0 {
+		desc := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[desc.Digest]; ok {
+			continue
+		}
+		seen[desc.Digest] = struct{}{}
+		flat = append(flat, desc)
+
+		if !IsManifestType(desc.MediaType) && !IsIndexType(desc.MediaType) {
+			continue
+		}
+		if desc.Size > walkReadLimit {
+			return nil, fmt.Errorf("%s exceeds walk read limit of %d bytes: %w", desc.Digest, walkReadLimit, errdefs.ErrInvalidArgument)
+		}
+		children, err := Children(ctx, provider, desc)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, children...)
+	}
+	return flat, nil
+}
+
 // WalkNotEmpty works the same way Walk does, with the exception that it ensures that
 // some children are still found by Walking the descriptors (for example, not all of
 // them have been filtered out by one of the handlers). If there are no children,
@@ -256,6 +389,41 @@ func SetChildrenMappedLabels(manager content.Manager, f HandlerFunc, labelMap fu
 	}
 }
 
+// DepthLimitHandler limits how deep into a manifest tree a walk may go,
+// failing with ErrMaxDepthExceeded when a descriptor is reached beyond max
+// levels, before the wrapped handler runs. The root is at depth 1. This
+// guards pull and unpack against crafted, pathologically nested indexes.
+func DepthLimitHandler(max int, h HandlerFunc) HandlerFunc {
+	var mu sync.Mutex
+	depths := map[digest.Digest]int{}
+	return func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		mu.Lock()
+		depth, ok := depths[desc.Digest]
+		if !ok {
+			depth = 1
+			depths[desc.Digest] = depth
+		}
+		mu.Unlock()
+		if depth > max {
+			return nil, fmt.Errorf("%s at depth %d exceeds limit %d: %w", desc.Digest, depth, max, ErrMaxDepthExceeded)
+		}
+		children, err := h(ctx, desc)
+		if err != nil {
+			return children, err
+		}
+		mu.Lock()
+		for _, c := range children {
+			// Keep the shallowest depth when a blob is shared between
+			// levels.
+			if d, ok := depths[c.Digest]; !ok || depth+1 < d {
+				depths[c.Digest] = depth + 1
+			}
+		}
+		mu.Unlock()
+		return children, nil
+	}
+}
+
 // FilterPlatforms is a handler wrapper which limits the descriptors returned
 // based on matching the specified platform matcher.
 func FilterPlatforms(f HandlerFunc, m platforms.Matcher) HandlerFunc {
@@ -0,0 +1,731 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/platforms"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// mapProvider serves blobs from a map keyed by digest without verifying
+// content, allowing tests to craft otherwise unconstructible trees such as
+// self-referencing indexes.
+type mapProvider map[digest.Digest][]byte
+
+func (p mapProvider) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (content.ReaderAt, error) {
+	b, ok := p[desc.Digest]
+	if !ok {
+		return nil, errdefs.ErrNotFound
+	}
+	return nopCloserAt{bytes.NewReader(b)}, nil
+}
+
+type nopCloserAt struct {
+	*bytes.Reader
+}
+
+func (nopCloserAt) Close() error { return nil }
+
+func TestWalkChildrenCycle(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	// An index whose only child descriptor carries the index's own digest.
+	// This cannot be produced by a digest-verifying store but can arrive
+	// from a malicious registry.
+	selfDgst := digest.FromString("self-referencing-index")
+	selfDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    selfDgst,
+	}
+	b, err := json.Marshal(ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{selfDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	selfDesc.Size = int64(len(b))
+	provider[selfDgst] = b
+
+	if err := WalkChildren(ctx, provider, selfDesc, nil); !errors.Is(err, ErrManifestCycle) {
+		t.Errorf("expected ErrManifestCycle, got %v", err)
+	}
+}
+
+func TestWalkChildrenAcyclic(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	config := []byte(`{}`)
+	configDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(config),
+		Size:      int64(len(config)),
+	}
+	provider[configDesc.Digest] = config
+
+	mb, err := json.Marshal(ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(mb),
+		Size:      int64(len(mb)),
+	}
+	provider[manifestDesc.Digest] = mb
+
+	// The same manifest listed twice is a valid DAG, not a cycle.
+	ib, err := json.Marshal(ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{manifestDesc, manifestDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(ib),
+		Size:      int64(len(ib)),
+	}
+	provider[indexDesc.Digest] = ib
+
+	if err := WalkChildren(ctx, provider, indexDesc, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPlatformFromDescriptorOrConfig(t *testing.T) {
+	ctx := context.Background()
+
+	config := []byte(`{"os":"linux","architecture":"arm64"}`)
+	configDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(config),
+		Size:      int64(len(config)),
+	}
+
+	t.Run("DescriptorPopulated", func(t *testing.T) {
+		desc := configDesc
+		desc.Platform = &ocispec.Platform{OS: "windows", Architecture: "amd64"}
+		// The provider is empty; a fully populated descriptor platform
+		// must be returned without any content read.
+		p, err := PlatformFromDescriptorOrConfig(ctx, mapProvider{}, desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.OS != "windows" || p.Architecture != "amd64" {
+			t.Errorf("unexpected platform %v", p)
+		}
+	})
+
+	t.Run("ConfigFallback", func(t *testing.T) {
+		provider := mapProvider{configDesc.Digest: config}
+		desc := configDesc
+		desc.Platform = &ocispec.Platform{OS: "linux"} // architecture missing
+		p, err := PlatformFromDescriptorOrConfig(ctx, provider, desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.OS != "linux" || p.Architecture != "arm64" {
+			t.Errorf("unexpected platform %v", p)
+		}
+	})
+}
+
+func TestChildrenIncludeSubject(t *testing.T) {
+	ctx := context.Background()
+
+	subject := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromString("subject-manifest"),
+		Size:      42,
+	}
+	config := []byte(`{}`)
+	configDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(config),
+		Size:      int64(len(config)),
+	}
+	mb, err := json.Marshal(ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+		Subject:   &subject,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(mb),
+		Size:      int64(len(mb)),
+	}
+	provider := mapProvider{manifestDesc.Digest: mb}
+
+	hasSubject := func(children []ocispec.Descriptor) bool {
+		for _, c := range children {
+			if c.Digest == subject.Digest {
+				return true
+			}
+		}
+		return false
+	}
+
+	children, err := Children(ctx, provider, manifestDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasSubject(children) {
+		t.Error("subject returned without WithIncludeSubject")
+	}
+
+	children, err = Children(ctx, provider, manifestDesc, WithIncludeSubject())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasSubject(children) {
+		t.Error("subject not returned with WithIncludeSubject")
+	}
+}
+
+func TestCollectAnnotations(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	config := []byte(`{}`)
+	configDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(config),
+		Size:      int64(len(config)),
+	}
+	provider[configDesc.Digest] = config
+
+	newManifest := func(annotations map[string]string) ocispec.Descriptor {
+		mb, err := json.Marshal(ocispec.Manifest{
+			MediaType:   ocispec.MediaTypeImageManifest,
+			Config:      configDesc,
+			Annotations: annotations,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType:   ocispec.MediaTypeImageManifest,
+			Digest:      digest.FromBytes(mb),
+			Size:        int64(len(mb)),
+			Annotations: annotations,
+		}
+		provider[desc.Digest] = mb
+		return desc
+	}
+
+	m1 := newManifest(map[string]string{"vnd.example.vulnerable": "CVE-2024-0001"})
+	m2 := newManifest(map[string]string{"org.opencontainers.image.created": "2024-01-01"})
+
+	ib, err := json.Marshal(ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{m1, m2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(ib),
+		Size:      int64(len(ib)),
+	}
+	provider[indexDesc.Digest] = ib
+
+	collected, err := CollectAnnotations(ctx, provider, indexDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collected) != 2 {
+		t.Fatalf("expected annotations for 2 descriptors, got %d", len(collected))
+	}
+	if v := collected[m1.Digest]["vnd.example.vulnerable"]; v != "CVE-2024-0001" {
+		t.Errorf("unexpected annotation %q", v)
+	}
+	if v := collected[m2.Digest]["org.opencontainers.image.created"]; v != "2024-01-01" {
+		t.Errorf("unexpected annotation %q", v)
+	}
+	if _, ok := collected[indexDesc.Digest]; ok {
+		t.Error("index without annotations should be omitted")
+	}
+}
+
+func TestCollectAnnotationsSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromString("oversized"),
+		Size:      walkReadLimit + 1,
+	}
+	if _, err := CollectAnnotations(ctx, mapProvider{}, desc); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument error, got %v", err)
+	}
+}
+
+func TestConfigDescriptor(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	config := []byte(`{"os":"linux","architecture":"amd64"}`)
+	configDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(config),
+		Size:      int64(len(config)),
+	}
+	mb, err := json.Marshal(ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(mb),
+		Size:      int64(len(mb)),
+	}
+	provider[manifestDesc.Digest] = mb
+
+	got, err := ConfigDescriptor(ctx, provider, manifestDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Digest != configDesc.Digest || got.MediaType != configDesc.MediaType {
+		t.Errorf("unexpected config descriptor %v", got)
+	}
+
+	indexDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromString("index"),
+	}
+	if _, err := ConfigDescriptor(ctx, provider, indexDesc); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument for index input, got %v", err)
+	}
+	layerDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.FromString("layer"),
+	}
+	if _, err := ConfigDescriptor(ctx, provider, layerDesc); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument for layer input, got %v", err)
+	}
+}
+
+func TestReachableBlobs(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	base := []byte("shared base layer")
+	baseDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.FromBytes(base),
+		Size:      int64(len(base)),
+	}
+
+	newImage := func(extra string) ocispec.Descriptor {
+		config := []byte(`{"image":"` + extra + `"}`)
+		configDesc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageConfig,
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		}
+		layer := []byte("unique layer " + extra)
+		layerDesc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageLayer,
+			Digest:    digest.FromBytes(layer),
+			Size:      int64(len(layer)),
+		}
+		mb, err := json.Marshal(ocispec.Manifest{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Config:    configDesc,
+			Layers:    []ocispec.Descriptor{baseDesc, layerDesc},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(mb),
+			Size:      int64(len(mb)),
+		}
+		provider[desc.Digest] = mb
+		return desc
+	}
+
+	img1 := newImage("one")
+	img2 := newImage("two")
+
+	reachable, err := ReachableBlobs(ctx, provider, []ocispec.Descriptor{img1, img2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 manifests + 2 configs + 2 unique layers + 1 shared base layer.
+	if len(reachable) != 7 {
+		t.Errorf("expected 7 reachable blobs, got %d", len(reachable))
+	}
+	for _, d := range []digest.Digest{img1.Digest, img2.Digest, baseDesc.Digest} {
+		if _, ok := reachable[d]; !ok {
+			t.Errorf("expected %s to be reachable", d)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	sharedConfig := []byte(`{"os":"linux"}`)
+	configDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(sharedConfig),
+		Size:      int64(len(sharedConfig)),
+	}
+
+	newManifest := func(arch string) ocispec.Descriptor {
+		layer := []byte("layer for " + arch)
+		layerDesc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageLayer,
+			Digest:    digest.FromBytes(layer),
+			Size:      int64(len(layer)),
+		}
+		mb, err := json.Marshal(ocispec.Manifest{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Config:    configDesc,
+			Layers:    []ocispec.Descriptor{layerDesc},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(mb),
+			Size:      int64(len(mb)),
+			Platform:  &ocispec.Platform{OS: "linux", Architecture: arch},
+		}
+		provider[desc.Digest] = mb
+		return desc
+	}
+
+	amd64 := newManifest("amd64")
+	arm64 := newManifest("arm64")
+
+	ib, err := json.Marshal(ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{amd64, arm64},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(ib),
+		Size:      int64(len(ib)),
+	}
+	provider[indexDesc.Digest] = ib
+
+	flat, err := Flatten(ctx, provider, indexDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Index, two manifests, shared config once, and the two layers.
+	if len(flat) != 6 {
+		t.Fatalf("expected 6 descriptors, got %d", len(flat))
+	}
+	if flat[0].Digest != indexDesc.Digest {
+		t.Errorf("expected index first, got %s", flat[0].Digest)
+	}
+	if flat[1].Digest != amd64.Digest || flat[2].Digest != arm64.Digest {
+		t.Error("expected manifests in index order after the index")
+	}
+	if flat[3].Digest != configDesc.Digest {
+		t.Errorf("expected shared config before layers, got %s", flat[3].Digest)
+	}
+	seen := map[digest.Digest]int{}
+	for _, d := range flat {
+		seen[d.Digest]++
+	}
+	for d, n := range seen {
+		if n != 1 {
+			t.Errorf("descriptor %s appears %d times", d, n)
+		}
+	}
+}
+
+func TestLayerDiff(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	newLayer := func(content string) ocispec.Descriptor {
+		b := []byte(content)
+		return ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageLayer,
+			Digest:    digest.FromBytes(b),
+			Size:      int64(len(b)),
+		}
+	}
+	base := newLayer("base layer")
+	shared := newLayer("shared layer")
+	oldOnly := newLayer("removed in v2")
+	newOnly1 := newLayer("added in v2 first")
+	newOnly2 := newLayer("added in v2 second")
+
+	newManifest := func(layers ...ocispec.Descriptor) ocispec.Descriptor {
+		config := []byte(`{"layers":` + strconv.Itoa(len(layers)) + `}`)
+		mb, err := json.Marshal(ocispec.Manifest{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Config: ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageConfig,
+				Digest:    digest.FromBytes(config),
+				Size:      int64(len(config)),
+			},
+			Layers: layers,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(mb),
+			Size:      int64(len(mb)),
+		}
+		provider[desc.Digest] = mb
+		return desc
+	}
+
+	v1 := newManifest(base, shared, oldOnly)
+	v2 := newManifest(base, shared, newOnly1, newOnly2)
+
+	added, removed, err := LayerDiff(ctx, provider, v1, v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 2 || added[0].Digest != newOnly1.Digest || added[1].Digest != newOnly2.Digest {
+		t.Errorf("unexpected added layers: %v", added)
+	}
+	if len(removed) != 1 || removed[0].Digest != oldOnly.Digest {
+		t.Errorf("unexpected removed layers: %v", removed)
+	}
+
+	// Identical manifests diff to nothing.
+	added, removed, err = LayerDiff(ctx, provider, v1, v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("expected empty diff, got added %v removed %v", added, removed)
+	}
+
+	// Unresolved indexes are rejected.
+	index := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageIndex}
+	if _, _, err := LayerDiff(ctx, provider, index, v2); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument for index, got %v", err)
+	}
+}
+
+func TestVerifyIndexPlatforms(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	newManifest := func(configJSON string) ocispec.Descriptor {
+		config := []byte(configJSON)
+		configDesc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageConfig,
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		}
+		provider[configDesc.Digest] = config
+		mb, err := json.Marshal(ocispec.Manifest{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Config:    configDesc,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(mb),
+			Size:      int64(len(mb)),
+		}
+		provider[desc.Digest] = mb
+		return desc
+	}
+
+	matching := newManifest(`{"os":"linux","architecture":"amd64"}`)
+	matching.Platform = &ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	mismatched := newManifest(`{"os":"linux","architecture":"arm64"}`)
+	mismatched.Platform = &ocispec.Platform{OS: "linux", Architecture: "amd64"}
+
+	newIndex := func(manifests ...ocispec.Descriptor) ocispec.Descriptor {
+		ib, err := json.Marshal(ocispec.Index{
+			MediaType: ocispec.MediaTypeImageIndex,
+			Manifests: manifests,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageIndex,
+			Digest:    digest.FromBytes(ib),
+			Size:      int64(len(ib)),
+		}
+		provider[desc.Digest] = ib
+		return desc
+	}
+
+	if err := VerifyIndexPlatforms(ctx, provider, newIndex(matching)); err != nil {
+		t.Errorf("expected matching index to verify, got %v", err)
+	}
+
+	err := VerifyIndexPlatforms(ctx, provider, newIndex(matching, mismatched))
+	if err == nil {
+		t.Fatal("expected error for mismatched platform entry")
+	}
+	if !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), mismatched.Digest.String()) {
+		t.Errorf("expected error to name the mismatched manifest, got %v", err)
+	}
+
+	if err := VerifyIndexPlatforms(ctx, provider, matching); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument for non-index input, got %v", err)
+	}
+}
+
+func TestImageConfig(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	newImage := func(arch string) ocispec.Descriptor {
+		config := []byte(`{"os":"linux","architecture":"` + arch + `","author":"` + arch + `-builder"}`)
+		configDesc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageConfig,
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		}
+		provider[configDesc.Digest] = config
+		mb, err := json.Marshal(ocispec.Manifest{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Config:    configDesc,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(mb),
+			Size:      int64(len(mb)),
+			Platform:  &ocispec.Platform{OS: "linux", Architecture: arch},
+		}
+		provider[desc.Digest] = mb
+		return desc
+	}
+
+	ib, err := json.Marshal(ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{newImage("amd64"), newImage("arm64")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(ib),
+		Size:      int64(len(ib)),
+	}
+	provider[indexDesc.Digest] = ib
+
+	config, configDesc, err := ImageConfig(ctx, provider, indexDesc, platforms.OnlyStrict(ocispec.Platform{OS: "linux", Architecture: "arm64"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Architecture != "arm64" {
+		t.Errorf("expected arm64 config, got %q", config.Architecture)
+	}
+	if config.Author != "arm64-builder" {
+		t.Errorf("unexpected author %q", config.Author)
+	}
+	if configDesc.Digest != digest.FromBytes(provider[configDesc.Digest]) {
+		t.Errorf("config descriptor does not match stored blob")
+	}
+
+	if _, _, err := ImageConfig(ctx, provider, indexDesc, platforms.OnlyStrict(ocispec.Platform{OS: "linux", Architecture: "riscv64"})); !errdefs.IsNotFound(err) {
+		t.Errorf("expected not found for unavailable platform, got %v", err)
+	}
+}
+
+func TestDepthLimitHandler(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	addJSON := func(mediaType string, v interface{}) ocispec.Descriptor {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType: mediaType,
+			Digest:    digest.FromBytes(b),
+			Size:      int64(len(b)),
+		}
+		provider[desc.Digest] = b
+		return desc
+	}
+
+	// depth 4: index -> index -> manifest -> config
+	manifest := addJSON(ocispec.MediaTypeImageManifest, ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    addJSON(ocispec.MediaTypeImageConfig, map[string]interface{}{"os": "linux"}),
+	})
+	inner := addJSON(ocispec.MediaTypeImageIndex, ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{manifest},
+	})
+	outer := addJSON(ocispec.MediaTypeImageIndex, ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{inner},
+	})
+
+	if err := Walk(ctx, DepthLimitHandler(4, ChildrenHandler(provider)), outer); err != nil {
+		t.Errorf("expected walk within limit to succeed, got %v", err)
+	}
+
+	err := Walk(ctx, DepthLimitHandler(2, ChildrenHandler(provider)), outer)
+	if !errors.Is(err, ErrMaxDepthExceeded) {
+		t.Errorf("expected ErrMaxDepthExceeded, got %v", err)
+	}
+}
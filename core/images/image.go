@@ -19,8 +19,10 @@ package images
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/containerd/containerd/v2/core/content"
@@ -330,8 +332,28 @@ func Check(ctx context.Context, provider content.Provider, image ocispec.Descrip
 	return true, required, present, missing, nil
 }
 
+// WalkOpt configures how Children interprets a manifest or index.
+type WalkOpt func(*walkConfig)
+
+type walkConfig struct {
+	includeSubject bool
+}
+
+// WithIncludeSubject includes the subject descriptor of a manifest or index
+// in the children returned by Children. The subject is excluded by default
+// so that garbage collection does not follow referrer links.
+func WithIncludeSubject() WalkOpt {
+	return func(c *walkConfig) {
+		c.includeSubject = true
+	}
+}
+
 // Children returns the immediate children of content described by the descriptor.
-func Children(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+func Children(ctx context.Context, provider content.Provider, desc ocispec.Descriptor, opts ...WalkOpt) ([]ocispec.Descriptor, error) {
+	var config walkConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
 	if IsManifestType(desc.MediaType) {
 		p, err := content.ReadBlob(ctx, provider, desc)
 		if err != nil {
@@ -349,7 +371,11 @@ func Children(ctx context.Context, provider content.Provider, desc ocispec.Descr
 			return nil, err
 		}
 
-		return append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...), nil
+		children := append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...)
+		if config.includeSubject && manifest.Subject != nil {
+			children = append(children, *manifest.Subject)
+		}
+		return children, nil
 	} else if IsIndexType(desc.MediaType) {
 		p, err := content.ReadBlob(ctx, provider, desc)
 		if err != nil {
@@ -365,7 +391,11 @@ func Children(ctx context.Context, provider content.Provider, desc ocispec.Descr
 			return nil, err
 		}
 
-		return append([]ocispec.Descriptor{}, index.Manifests...), nil
+		children := append([]ocispec.Descriptor{}, index.Manifests...)
+		if config.includeSubject && index.Subject != nil {
+			children = append(children, *index.Subject)
+		}
+		return children, nil
 	} else if !IsLayerType(desc.MediaType) && !IsKnownConfig(desc.MediaType) {
 		// Layers and configs are childless data types and should not be logged.
 		log.G(ctx).Debugf("encountered unknown type %v; children may not be fetched", desc.MediaType)
@@ -373,6 +403,94 @@ func Children(ctx context.Context, provider content.Provider, desc ocispec.Descr
 	return nil, nil
 }
 
+// ConfigDescriptor returns the config descriptor referenced by the provided
+// manifest without materializing the rest of the manifest. Index descriptors
+// are rejected since an index has no single config; resolve a
+// platform-specific manifest first.
+func ConfigDescriptor(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) (ocispec.Descriptor, error) {
+	if IsIndexType(desc.MediaType) {
+		return ocispec.Descriptor{}, fmt.Errorf("%s is an index; resolve a platform-specific manifest first: %w", desc.Digest, errdefs.ErrInvalidArgument)
+	}
+	if !IsManifestType(desc.MediaType) {
+		return ocispec.Descriptor{}, fmt.Errorf("%s (%s) is not a manifest: %w", desc.Digest, desc.MediaType, errdefs.ErrInvalidArgument)
+	}
+
+	p, err := content.ReadBlob(ctx, provider, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := validateMediaType(p, desc.MediaType); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("config descriptor: invalid desc %s: %w", desc.Digest, err)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return manifest.Config, nil
+}
+
+// LayerDiff computes the symmetric difference of the layer sets of two
+// manifests, assumed to target the same platform. It returns the layers
+// present in b but not a (added) and those present in a but not b (removed),
+// each preserving the order of its manifest. Both descriptors must be
+// manifests; an unresolved index is rejected with ErrInvalidArgument.
+func LayerDiff(ctx context.Context, provider content.Provider, a, b ocispec.Descriptor) (added, removed []ocispec.Descriptor, err error) {
+	layersA, err := manifestLayers(ctx, provider, a)
+	if err != nil {
+		return nil, nil, err
+	}
+	layersB, err := manifestLayers(ctx, provider, b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inA := make(map[digest.Digest]struct{}, len(layersA))
+	for _, l := range layersA {
+		inA[l.Digest] = struct{}{}
+	}
+	inB := make(map[digest.Digest]struct{}, len(layersB))
+	for _, l := range layersB {
+		inB[l.Digest] = struct{}{}
+	}
+
+	for _, l := range layersB {
+		if _, ok := inA[l.Digest]; !ok {
+			added = append(added, l)
+		}
+	}
+	for _, l := range layersA {
+		if _, ok := inB[l.Digest]; !ok {
+			removed = append(removed, l)
+		}
+	}
+	return added, removed, nil
+}
+
+// manifestLayers reads the layer list of a manifest descriptor.
+func manifestLayers(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	if IsIndexType(desc.MediaType) {
+		return nil, fmt.Errorf("%s is an index; resolve a platform-specific manifest first: %w", desc.Digest, errdefs.ErrInvalidArgument)
+	}
+	if !IsManifestType(desc.MediaType) {
+		return nil, fmt.Errorf("%s (%s) is not a manifest: %w", desc.Digest, desc.MediaType, errdefs.ErrInvalidArgument)
+	}
+
+	p, err := content.ReadBlob(ctx, provider, desc)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMediaType(p, desc.MediaType); err != nil {
+		return nil, fmt.Errorf("layer diff: invalid desc %s: %w", desc.Digest, err)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest.Layers, nil
+}
+
 // unknownDocument represents a manifest, manifest list, or index that has not
 // yet been validated.
 type unknownDocument struct {
@@ -383,6 +501,43 @@ type unknownDocument struct {
 	FSLayers  json.RawMessage `json:"fsLayers,omitempty"` // schema 1
 }
 
+// DetectMediaType classifies raw manifest bytes received without a
+// descriptor, returning the canonical media type: the document's own
+// mediaType field when present, otherwise the OCI type inferred from its
+// shape (manifests for an index, config/layers for a manifest, a platform
+// document for a config). Schema 1 manifests and unrecognized documents are
+// rejected with ErrInvalidArgument.
+func DetectMediaType(data []byte) (string, error) {
+	var doc struct {
+		unknownDocument
+		Architecture string          `json:"architecture,omitempty"` // config
+		RootFS       json.RawMessage `json:"rootfs,omitempty"`       // config
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", err
+	}
+	if len(doc.FSLayers) != 0 {
+		return "", fmt.Errorf("media-type: schema 1 not supported: %w", errdefs.ErrInvalidArgument)
+	}
+	if doc.MediaType != "" {
+		switch {
+		case IsIndexType(doc.MediaType) && (len(doc.Config) != 0 || len(doc.Layers) != 0),
+			IsManifestType(doc.MediaType) && len(doc.Manifests) != 0:
+			return "", fmt.Errorf("media-type: document shape contradicts declared %s: %w", doc.MediaType, errdefs.ErrInvalidArgument)
+		}
+		return doc.MediaType, nil
+	}
+	switch {
+	case len(doc.Manifests) != 0:
+		return ocispec.MediaTypeImageIndex, nil
+	case len(doc.Config) != 0 || len(doc.Layers) != 0:
+		return ocispec.MediaTypeImageManifest, nil
+	case len(doc.RootFS) != 0 || doc.Architecture != "":
+		return ocispec.MediaTypeImageConfig, nil
+	}
+	return "", fmt.Errorf("media-type: unrecognized document: %w", errdefs.ErrInvalidArgument)
+}
+
 // validateMediaType returns an error if the byte slice is invalid JSON,
 // if the format of the blob is not supported, or if the media type
 // identifies the blob as one format, but it identifies itself as, or
@@ -420,6 +575,37 @@ func RootFS(ctx context.Context, provider content.Provider, configDesc ocispec.D
 	return config.RootFS.DiffIDs, nil
 }
 
+// ContentID computes a stable identifier for the image content referenced by
+// a manifest, derived from the config digest and the ordered rootfs diff IDs.
+// Manifests differing only in annotations or layer compression yield the same
+// ContentID, making it suitable for deduplicating logically-identical images
+// regardless of tag or packaging.
+func ContentID(ctx context.Context, provider content.Provider, manifestDesc ocispec.Descriptor) (digest.Digest, error) {
+	if !IsManifestType(manifestDesc.MediaType) {
+		return "", fmt.Errorf("%s must be a manifest: %w", manifestDesc.MediaType, errdefs.ErrInvalidArgument)
+	}
+	p, err := content.ReadBlob(ctx, provider, manifestDesc)
+	if err != nil {
+		return "", err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		return "", err
+	}
+	diffIDs, err := RootFS(ctx, provider, manifest.Config)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(manifest.Config.Digest.String())
+	for _, d := range diffIDs {
+		sb.WriteString("\n")
+		sb.WriteString(d.String())
+	}
+	return digest.FromString(sb.String()), nil
+}
+
 // ConfigPlatform returns a normalized platform from an image manifest config.
 func ConfigPlatform(ctx context.Context, provider content.Provider, configDesc ocispec.Descriptor) (ocispec.Platform, error) {
 	p, err := content.ReadBlob(ctx, provider, configDesc)
@@ -435,3 +621,258 @@ func ConfigPlatform(ctx context.Context, provider content.Provider, configDesc o
 	}
 	return platforms.Normalize(imagePlatform), nil
 }
+
+// CreatedTime returns the creation timestamp recorded in the config of the
+// provided manifest, or the zero time if the config does not set one. Index
+// descriptors are rejected; resolve a platform-specific manifest first.
+func CreatedTime(ctx context.Context, provider content.Provider, manifestDesc ocispec.Descriptor) (time.Time, error) {
+	configDesc, err := ConfigDescriptor(ctx, provider, manifestDesc)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	p, err := content.ReadBlob(ctx, provider, configDesc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var config ocispec.Image
+	if err := json.Unmarshal(p, &config); err != nil {
+		return time.Time{}, err
+	}
+	if config.Created == nil {
+		return time.Time{}, nil
+	}
+	return *config.Created, nil
+}
+
+// CanonicalManifestBytes returns the stored bytes of the manifest at desc
+// verbatim, after verifying they hash to desc.Digest. No re-marshaling takes
+// place, so signers see exactly the content-addressed bytes; a provider
+// serving bytes which do not match the digest is rejected.
+func CanonicalManifestBytes(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) ([]byte, error) {
+	if !IsManifestType(desc.MediaType) && !IsIndexType(desc.MediaType) {
+		return nil, fmt.Errorf("%s (%s) is not a manifest or index: %w", desc.Digest, desc.MediaType, errdefs.ErrInvalidArgument)
+	}
+	if err := desc.Digest.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid digest %q: %w", desc.Digest, errdefs.ErrInvalidArgument)
+	}
+
+	p, err := content.ReadBlob(ctx, provider, desc)
+	if err != nil {
+		return nil, err
+	}
+	if dgst := desc.Digest.Algorithm().FromBytes(p); dgst != desc.Digest {
+		return nil, fmt.Errorf("stored bytes digest to %s, expected %s: %w", dgst, desc.Digest, errdefs.ErrFailedPrecondition)
+	}
+	return p, nil
+}
+
+// RunConfig resolves the image at desc to a manifest for the given platform
+// and returns the runtime subset of its config: environment, entrypoint,
+// command, working directory, and so on. It saves callers the
+// resolve-read-unmarshal dance when preparing a container from an image.
+func RunConfig(ctx context.Context, provider content.Provider, desc ocispec.Descriptor, platform platforms.MatchComparer) (ocispec.ImageConfig, error) {
+	configDesc, err := Config(ctx, provider, desc, platform)
+	if err != nil {
+		return ocispec.ImageConfig{}, err
+	}
+
+	p, err := content.ReadBlob(ctx, provider, configDesc)
+	if err != nil {
+		return ocispec.ImageConfig{}, err
+	}
+	var config ocispec.Image
+	if err := json.Unmarshal(p, &config); err != nil {
+		return ocispec.ImageConfig{}, err
+	}
+	return config.Config, nil
+}
+
+// EffectiveAnnotations returns the union of the annotations attached to an
+// image at each level: the config's labels, the index entry pointing at the
+// platform manifest, and the manifest itself. Overlapping keys resolve with
+// manifest annotations over index-entry annotations over config labels. When
+// desc is a manifest rather than an index, its own descriptor annotations
+// take the index-entry position.
+func EffectiveAnnotations(ctx context.Context, provider content.Provider, desc ocispec.Descriptor, platform platforms.MatchComparer) (map[string]string, error) {
+	manifestDesc := desc
+	if IsIndexType(desc.MediaType) {
+		p, err := content.ReadBlob(ctx, provider, desc)
+		if err != nil {
+			return nil, err
+		}
+		var idx ocispec.Index
+		if err := json.Unmarshal(p, &idx); err != nil {
+			return nil, err
+		}
+
+		var descs []ocispec.Descriptor
+		for _, d := range idx.Manifests {
+			if platform == nil || d.Platform == nil || platform.Match(*d.Platform) {
+				descs = append(descs, d)
+			}
+		}
+		if platform != nil {
+			sort.SliceStable(descs, func(i, j int) bool {
+				if descs[i].Platform == nil {
+					return false
+				}
+				if descs[j].Platform == nil {
+					return true
+				}
+				return platform.Less(*descs[i].Platform, *descs[j].Platform)
+			})
+		}
+		if len(descs) == 0 {
+			return nil, fmt.Errorf("no match for platform in manifest %v: %w", desc.Digest, errdefs.ErrNotFound)
+		}
+		manifestDesc = descs[0]
+	} else if !IsManifestType(desc.MediaType) {
+		return nil, fmt.Errorf("media type %q is not a manifest or index: %w", desc.MediaType, errdefs.ErrInvalidArgument)
+	}
+
+	p, err := content.ReadBlob(ctx, provider, manifestDesc)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		return nil, err
+	}
+
+	p, err = content.ReadBlob(ctx, provider, manifest.Config)
+	if err != nil {
+		return nil, err
+	}
+	var config ocispec.Image
+	if err := json.Unmarshal(p, &config); err != nil {
+		return nil, err
+	}
+
+	merged := map[string]string{}
+	for k, v := range config.Config.Labels {
+		merged[k] = v
+	}
+	for k, v := range manifestDesc.Annotations {
+		merged[k] = v
+	}
+	for k, v := range manifest.Annotations {
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+// PlatformFromDescriptorOrConfig returns the platform already carried by the
+// descriptor when it is fully populated (OS and Architecture set), only
+// falling back to reading the config blob otherwise. This avoids an
+// unnecessary content read for fat-manifest children which embed platform
+// information.
+func PlatformFromDescriptorOrConfig(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) (ocispec.Platform, error) {
+	if p := desc.Platform; p != nil && p.OS != "" && p.Architecture != "" {
+		return platforms.Normalize(*p), nil
+	}
+	return ConfigPlatform(ctx, provider, desc)
+}
+
+// VerifyIndexPlatforms checks that every manifest entry of the given index
+// which declares a Platform agrees with the platform recorded in that
+// manifest's config. Registries occasionally serve fat manifests whose index
+// entries disagree with the child configs, which silently breaks platform
+// selection; this surfaces such indexes before push. All mismatches are
+// reported, one error per entry.
+func VerifyIndexPlatforms(ctx context.Context, provider content.Provider, desc ocispec.Descriptor) error {
+	if !IsIndexType(desc.MediaType) {
+		return fmt.Errorf("%s (%s) is not an index: %w", desc.Digest, desc.MediaType, errdefs.ErrInvalidArgument)
+	}
+
+	p, err := content.ReadBlob(ctx, provider, desc)
+	if err != nil {
+		return err
+	}
+	if err := validateMediaType(p, desc.MediaType); err != nil {
+		return fmt.Errorf("verify index platforms: invalid desc %s: %w", desc.Digest, err)
+	}
+
+	var index ocispec.Index
+	if err := json.Unmarshal(p, &index); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, m := range index.Manifests {
+		if !IsManifestType(m.MediaType) || m.Platform == nil {
+			continue
+		}
+		configDesc, err := ConfigDescriptor(ctx, provider, m)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("manifest %s: %w", m.Digest, err))
+			continue
+		}
+		actual, err := ConfigPlatform(ctx, provider, configDesc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("manifest %s config: %w", m.Digest, err))
+			continue
+		}
+		declared := platforms.Normalize(*m.Platform)
+		if declared.OS != actual.OS || declared.Architecture != actual.Architecture ||
+			(declared.Variant != "" && actual.Variant != "" && declared.Variant != actual.Variant) {
+			errs = append(errs, fmt.Errorf("manifest %s declares platform %s but its config reports %s: %w",
+				m.Digest, platforms.Format(declared), platforms.Format(actual), errdefs.ErrInvalidArgument))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DescriptorEqual reports whether two descriptors identify the same content:
+// digest, size, and media type must match. When ignoreAnnotations is true the
+// comparison stops there, so descriptors differing only in volatile fields
+// such as annotations compare equal; otherwise annotations and platform must
+// also match.
+func DescriptorEqual(a, b ocispec.Descriptor, ignoreAnnotations bool) bool {
+	if a.Digest != b.Digest || a.Size != b.Size || a.MediaType != b.MediaType {
+		return false
+	}
+	if ignoreAnnotations {
+		return true
+	}
+	if len(a.Annotations) != len(b.Annotations) {
+		return false
+	}
+	for k, v := range a.Annotations {
+		if bv, ok := b.Annotations[k]; !ok || bv != v {
+			return false
+		}
+	}
+	if (a.Platform == nil) != (b.Platform == nil) {
+		return false
+	}
+	if a.Platform != nil {
+		pa, pb := platforms.Normalize(*a.Platform), platforms.Normalize(*b.Platform)
+		if pa.OS != pb.OS || pa.Architecture != pb.Architecture || pa.Variant != pb.Variant {
+			return false
+		}
+	}
+	return true
+}
+
+// ImageConfig resolves the image to a platform-specific manifest, reads the
+// config blob it references, and returns the parsed config along with its
+// descriptor — the full resolve-manifest-config sequence which pull and
+// inspect paths otherwise spell out with three store round-trips.
+func ImageConfig(ctx context.Context, provider content.Provider, desc ocispec.Descriptor, matcher platforms.MatchComparer) (ocispec.Image, ocispec.Descriptor, error) {
+	manifest, err := Manifest(ctx, provider, desc, matcher)
+	if err != nil {
+		return ocispec.Image{}, ocispec.Descriptor{}, err
+	}
+
+	p, err := content.ReadBlob(ctx, provider, manifest.Config)
+	if err != nil {
+		return ocispec.Image{}, ocispec.Descriptor{}, err
+	}
+
+	var config ocispec.Image
+	if err := json.Unmarshal(p, &config); err != nil {
+		return ocispec.Image{}, ocispec.Descriptor{}, err
+	}
+	return config, manifest.Config, nil
+}
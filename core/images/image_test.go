@@ -17,9 +17,14 @@
 package images
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/containerd/errdefs"
+	"github.com/containerd/platforms"
+	digest "github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -125,3 +130,443 @@ func TestValidateMediaType(t *testing.T) {
 		assert.Error(t, err, "document should not be valid")
 	})
 }
+
+func TestDescriptorEqual(t *testing.T) {
+	base := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    "sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b",
+		Size:      100,
+	}
+	annotated := base
+	annotated.Annotations = map[string]string{"org.opencontainers.image.created": "2024-01-01"}
+	resized := base
+	resized.Size = 200
+	withPlatform := base
+	withPlatform.Platform = &ocispec.Platform{OS: "linux", Architecture: "amd64"}
+
+	for _, tc := range []struct {
+		name              string
+		a, b              ocispec.Descriptor
+		ignoreAnnotations bool
+		expected          bool
+	}{
+		{
+			name:     "Identical",
+			a:        base,
+			b:        base,
+			expected: true,
+		},
+		{
+			name:              "DifferentAnnotationsIgnored",
+			a:                 base,
+			b:                 annotated,
+			ignoreAnnotations: true,
+			expected:          true,
+		},
+		{
+			name:     "DifferentAnnotationsCompared",
+			a:        base,
+			b:        annotated,
+			expected: false,
+		},
+		{
+			name:              "DifferentSize",
+			a:                 base,
+			b:                 resized,
+			ignoreAnnotations: true,
+			expected:          false,
+		},
+		{
+			name:              "PlatformIgnored",
+			a:                 base,
+			b:                 withPlatform,
+			ignoreAnnotations: true,
+			expected:          true,
+		},
+		{
+			name:     "PlatformCompared",
+			a:        base,
+			b:        withPlatform,
+			expected: false,
+		},
+		{
+			name:     "SamePlatform",
+			a:        withPlatform,
+			b:        withPlatform,
+			expected: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DescriptorEqual(tc.a, tc.b, tc.ignoreAnnotations); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDetectMediaType(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		doc      string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "OCIIndexDeclared",
+			doc:      `{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[]}`,
+			expected: ocispec.MediaTypeImageIndex,
+		},
+		{
+			name:     "DockerManifestListDeclared",
+			doc:      `{"mediaType":"application/vnd.docker.distribution.manifest.list.v2+json","manifests":[{"digest":"sha256:abc"}]}`,
+			expected: MediaTypeDockerSchema2ManifestList,
+		},
+		{
+			name:     "OCIManifestDeclared",
+			doc:      `{"mediaType":"application/vnd.oci.image.manifest.v1+json","config":{},"layers":[]}`,
+			expected: ocispec.MediaTypeImageManifest,
+		},
+		{
+			name:     "IndexInferredFromShape",
+			doc:      `{"schemaVersion":2,"manifests":[{"digest":"sha256:abc"}]}`,
+			expected: ocispec.MediaTypeImageIndex,
+		},
+		{
+			name:     "ManifestInferredFromShape",
+			doc:      `{"schemaVersion":2,"config":{"digest":"sha256:abc"},"layers":[]}`,
+			expected: ocispec.MediaTypeImageManifest,
+		},
+		{
+			name:     "ConfigInferredFromShape",
+			doc:      `{"architecture":"amd64","os":"linux","rootfs":{"type":"layers","diff_ids":[]}}`,
+			expected: ocispec.MediaTypeImageConfig,
+		},
+		{
+			name:    "Schema1Rejected",
+			doc:     `{"schemaVersion":1,"fsLayers":[{"blobSum":"sha256:abc"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "ContradictoryShape",
+			doc:     `{"mediaType":"application/vnd.oci.image.index.v1+json","config":{},"layers":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Unrecognized",
+			doc:     `{"hello":"world"}`,
+			wantErr: true,
+		},
+		{
+			name:    "InvalidJSON",
+			doc:     `{`,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt, err := DetectMediaType([]byte(tc.doc))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", mt)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mt != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, mt)
+			}
+		})
+	}
+}
+
+func TestContentID(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	diffIDs := []digest.Digest{
+		digest.FromString("diff-1"),
+		digest.FromString("diff-2"),
+	}
+	configJSON, err := json.Marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"rootfs":       map[string]interface{}{"type": "layers", "diff_ids": diffIDs},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(configJSON),
+		Size:      int64(len(configJSON)),
+	}
+	provider[config.Digest] = configJSON
+
+	newManifest := func(layerMediaType, layerContent string, annotations map[string]string) ocispec.Descriptor {
+		manifest := ocispec.Manifest{
+			Config: config,
+			Layers: []ocispec.Descriptor{{
+				MediaType: layerMediaType,
+				Digest:    digest.FromString(layerContent),
+				Size:      int64(len(layerContent)),
+			}},
+			Annotations: annotations,
+		}
+		b, err := json.Marshal(manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(b),
+			Size:      int64(len(b)),
+		}
+		provider[desc.Digest] = b
+		return desc
+	}
+
+	// Same config and diff IDs packaged with different compression and
+	// annotations must produce the same ID.
+	gzipped := newManifest(ocispec.MediaTypeImageLayerGzip, "gzip bytes", nil)
+	zstded := newManifest(ocispec.MediaTypeImageLayerZstd, "zstd bytes", map[string]string{"note": "recompressed"})
+	if gzipped.Digest == zstded.Digest {
+		t.Fatal("expected distinct manifest digests")
+	}
+
+	id1, err := ContentID(ctx, provider, gzipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id2, err := ContentID(ctx, provider, zstded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id1 != id2 {
+		t.Errorf("expected matching content IDs, got %s and %s", id1, id2)
+	}
+
+	// A genuinely different image differs.
+	otherConfigJSON, err := json.Marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"rootfs": map[string]interface{}{
+			"type":     "layers",
+			"diff_ids": []digest.Digest{digest.FromString("other diff")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherConfig := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(otherConfigJSON),
+		Size:      int64(len(otherConfigJSON)),
+	}
+	provider[otherConfig.Digest] = otherConfigJSON
+	config = otherConfig
+	other := newManifest(ocispec.MediaTypeImageLayerGzip, "other bytes", nil)
+
+	id3, err := ContentID(ctx, provider, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id3 == id1 {
+		t.Error("expected different content ID for different image")
+	}
+
+	if _, err := ContentID(ctx, provider, ocispec.Descriptor{MediaType: ocispec.MediaTypeImageIndex}); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument for non-manifest, got %v", err)
+	}
+}
+
+func TestCreatedTime(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	created := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+
+	writeConfig := func(img ocispec.Image) ocispec.Descriptor {
+		b, err := json.Marshal(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageConfig,
+			Digest:    digest.FromBytes(b),
+			Size:      int64(len(b)),
+		}
+		provider[desc.Digest] = b
+		return desc
+	}
+	writeManifest := func(config ocispec.Descriptor) ocispec.Descriptor {
+		b, err := json.Marshal(ocispec.Manifest{Config: config})
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(b),
+			Size:      int64(len(b)),
+		}
+		provider[desc.Digest] = b
+		return desc
+	}
+
+	withCreated := writeManifest(writeConfig(ocispec.Image{Created: &created}))
+	got, err := CreatedTime(ctx, provider, withCreated)
+	require.NoError(t, err)
+	assert.True(t, got.Equal(created), "expected %v, got %v", created, got)
+
+	withoutCreated := writeManifest(writeConfig(ocispec.Image{}))
+	got, err = CreatedTime(ctx, provider, withoutCreated)
+	require.NoError(t, err)
+	assert.True(t, got.IsZero(), "expected zero time, got %v", got)
+
+	_, err = CreatedTime(ctx, provider, ocispec.Descriptor{MediaType: ocispec.MediaTypeImageIndex})
+	assert.True(t, errdefs.IsInvalidArgument(err), "expected invalid argument for index, got %v", err)
+}
+
+func TestRunConfig(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		Config: ocispec.ImageConfig{
+			Env:        []string{"PATH=/usr/bin", "APP_MODE=prod"},
+			Entrypoint: []string{"/entrypoint.sh"},
+			Cmd:        []string{"serve", "--port=8080"},
+			WorkingDir: "/srv",
+		},
+	})
+	require.NoError(t, err)
+	configDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(configJSON),
+		Size:      int64(len(configJSON)),
+	}
+	provider[configDesc.Digest] = configJSON
+
+	manifestJSON, err := json.Marshal(ocispec.Manifest{Config: configDesc})
+	require.NoError(t, err)
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifestJSON),
+		Size:      int64(len(manifestJSON)),
+	}
+	provider[manifestDesc.Digest] = manifestJSON
+
+	cfg, err := RunConfig(ctx, provider, manifestDesc, platforms.All)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PATH=/usr/bin", "APP_MODE=prod"}, cfg.Env)
+	assert.Equal(t, []string{"/entrypoint.sh"}, cfg.Entrypoint)
+	assert.Equal(t, []string{"serve", "--port=8080"}, cfg.Cmd)
+	assert.Equal(t, "/srv", cfg.WorkingDir)
+}
+
+func TestEffectiveAnnotations(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		Config: ocispec.ImageConfig{
+			Labels: map[string]string{
+				"only.config":   "config",
+				"also.index":    "config",
+				"also.manifest": "config",
+			},
+		},
+	})
+	require.NoError(t, err)
+	configDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(configJSON),
+		Size:      int64(len(configJSON)),
+	}
+	provider[configDesc.Digest] = configJSON
+
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Config: configDesc,
+		Annotations: map[string]string{
+			"also.manifest": "manifest",
+			"only.manifest": "manifest",
+		},
+	})
+	require.NoError(t, err)
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifestJSON),
+		Size:      int64(len(manifestJSON)),
+		Platform:  &ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		Annotations: map[string]string{
+			"also.index":    "index",
+			"also.manifest": "index",
+			"only.index":    "index",
+		},
+	}
+	provider[manifestDesc.Digest] = manifestJSON
+
+	indexJSON, err := json.Marshal(ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{manifestDesc},
+	})
+	require.NoError(t, err)
+	indexDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(indexJSON),
+		Size:      int64(len(indexJSON)),
+	}
+	provider[indexDesc.Digest] = indexJSON
+
+	merged, err := EffectiveAnnotations(ctx, provider, indexDesc, platforms.All)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"only.config":   "config",
+		"only.index":    "index",
+		"only.manifest": "manifest",
+		"also.index":    "index",
+		"also.manifest": "manifest",
+	}, merged)
+
+	// A config descriptor is neither a manifest nor an index.
+	_, err = EffectiveAnnotations(ctx, provider, configDesc, platforms.All)
+	assert.True(t, errdefs.IsInvalidArgument(err))
+}
+
+func TestCanonicalManifestBytes(t *testing.T) {
+	ctx := context.Background()
+	provider := mapProvider{}
+
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    ocispec.Descriptor{MediaType: ocispec.MediaTypeImageConfig, Digest: digest.FromString("config"), Size: 6},
+	})
+	require.NoError(t, err)
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifestJSON),
+		Size:      int64(len(manifestJSON)),
+	}
+	provider[desc.Digest] = manifestJSON
+
+	b, err := CanonicalManifestBytes(ctx, provider, desc)
+	require.NoError(t, err)
+	assert.Equal(t, manifestJSON, b)
+	assert.Equal(t, desc.Digest, digest.FromBytes(b))
+
+	// A provider serving different bytes for the digest is rejected.
+	tampered := append([]byte(nil), manifestJSON...)
+	tampered[0] = ' '
+	provider[desc.Digest] = tampered
+	_, err = CanonicalManifestBytes(ctx, provider, desc)
+	assert.True(t, errdefs.IsFailedPrecondition(err), "expected digest mismatch, got %v", err)
+
+	// Only manifests and indexes are canonicalizable.
+	_, err = CanonicalManifestBytes(ctx, provider, ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    desc.Digest,
+	})
+	assert.True(t, errdefs.IsInvalidArgument(err), "expected invalid argument, got %v", err)
+}
@@ -0,0 +1,148 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// StripNonDistributableLayers removes non-distributable ("foreign") layers
+// from a manifest and returns the descriptor of the rewritten manifest,
+// allowing the image to be pushed to registries which reject such layers.
+// When the config's rootfs diff IDs parallel the layer list, the diff IDs of
+// the removed layers are dropped as well and a rewritten config is
+// referenced. If the manifest has no non-distributable layers it is returned
+// unchanged.
+func StripNonDistributableLayers(ctx context.Context, cs content.Store, manifestDesc ocispec.Descriptor) (ocispec.Descriptor, error) {
+	if !IsManifestType(manifestDesc.MediaType) {
+		return ocispec.Descriptor{}, fmt.Errorf("%s must be a manifest: %w", manifestDesc.MediaType, errdefs.ErrInvalidArgument)
+	}
+	b, err := content.ReadBlob(ctx, cs, manifestDesc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	var (
+		layers  = make([]ocispec.Descriptor, 0, len(manifest.Layers))
+		removed []int
+	)
+	for i, l := range manifest.Layers {
+		if IsNonDistributable(l.MediaType) {
+			removed = append(removed, i)
+			continue
+		}
+		layers = append(layers, l)
+	}
+	if len(removed) == 0 {
+		return manifestDesc, nil
+	}
+
+	newConfig, err := stripConfigDiffIDs(ctx, cs, manifest.Config, len(manifest.Layers), removed)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	manifest.Config = newConfig
+	manifest.Layers = layers
+	mb, err := json.Marshal(manifest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	newDesc := manifestDesc
+	newDesc.Digest = digest.FromBytes(mb)
+	newDesc.Size = int64(len(mb))
+
+	labels := map[string]string{
+		"containerd.io/gc.ref.content.config": manifest.Config.Digest.String(),
+	}
+	for i, l := range manifest.Layers {
+		labels[fmt.Sprintf("containerd.io/gc.ref.content.l.%d", i)] = l.Digest.String()
+	}
+	if err := content.WriteBlob(ctx, cs, "manifest-"+newDesc.Digest.String(), bytes.NewReader(mb), newDesc, content.WithLabels(labels)); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return newDesc, nil
+}
+
+// stripConfigDiffIDs rewrites the config to drop the rootfs diff IDs at the
+// removed layer indexes, preserving all other config fields. If the diff ID
+// count does not parallel the layer list the config is left untouched, since
+// the correspondence cannot be established.
+func stripConfigDiffIDs(ctx context.Context, cs content.Store, configDesc ocispec.Descriptor, layerCount int, removed []int) (ocispec.Descriptor, error) {
+	b, err := content.ReadBlob(ctx, cs, configDesc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(b, &config); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	var rootfs struct {
+		Type    string          `json:"type"`
+		DiffIDs []digest.Digest `json:"diff_ids"`
+	}
+	if err := json.Unmarshal(config["rootfs"], &rootfs); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if len(rootfs.DiffIDs) != layerCount {
+		return configDesc, nil
+	}
+
+	removedSet := make(map[int]struct{}, len(removed))
+	for _, i := range removed {
+		removedSet[i] = struct{}{}
+	}
+	diffIDs := make([]digest.Digest, 0, len(rootfs.DiffIDs)-len(removed))
+	for i, d := range rootfs.DiffIDs {
+		if _, ok := removedSet[i]; ok {
+			continue
+		}
+		diffIDs = append(diffIDs, d)
+	}
+	rootfs.DiffIDs = diffIDs
+
+	rb, err := json.Marshal(rootfs)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	config["rootfs"] = rb
+	cb, err := json.Marshal(config)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	newDesc := configDesc
+	newDesc.Digest = digest.FromBytes(cb)
+	newDesc.Size = int64(len(cb))
+	if err := content.WriteBlob(ctx, cs, "config-"+newDesc.Digest.String(), bytes.NewReader(cb), newDesc); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return newDesc, nil
+}
@@ -0,0 +1,147 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func writeTestBlob(t *testing.T, cs content.Store, mediaType string, b []byte) ocispec.Descriptor {
+	t.Helper()
+	desc := ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(b),
+		Size:      int64(len(b)),
+	}
+	if err := content.WriteBlob(context.Background(), cs, "test-"+desc.Digest.String(), bytes.NewReader(b), desc); err != nil {
+		t.Fatal(err)
+	}
+	return desc
+}
+
+func TestStripNonDistributableLayers(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := writeTestBlob(t, cs, ocispec.MediaTypeImageLayerGzip, []byte("ordinary layer"))
+	foreign := writeTestBlob(t, cs, MediaTypeDockerSchema2LayerForeignGzip, []byte("foreign layer"))
+
+	configJSON, err := json.Marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"author":       "test",
+		"rootfs": map[string]interface{}{
+			"type": "layers",
+			"diff_ids": []string{
+				digest.FromString("ordinary diff").String(),
+				digest.FromString("foreign diff").String(),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := writeTestBlob(t, cs, ocispec.MediaTypeImageConfig, configJSON)
+
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layer, foreign},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := writeTestBlob(t, cs, ocispec.MediaTypeImageManifest, manifestJSON)
+
+	newDesc, err := StripNonDistributableLayers(ctx, cs, manifestDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newDesc.Digest == manifestDesc.Digest {
+		t.Fatal("expected a rewritten manifest")
+	}
+
+	p, err := content.ReadBlob(ctx, cs, newDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Layers) != 1 || manifest.Layers[0].Digest != layer.Digest {
+		t.Errorf("unexpected layers %v, expected only %s", manifest.Layers, layer.Digest)
+	}
+	if manifest.Config.Digest == config.Digest {
+		t.Error("expected a rewritten config")
+	}
+
+	cp, err := content.ReadBlob(ctx, cs, manifest.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var newConfig struct {
+		Author string `json:"author"`
+		RootFS struct {
+			DiffIDs []digest.Digest `json:"diff_ids"`
+		} `json:"rootfs"`
+	}
+	if err := json.Unmarshal(cp, &newConfig); err != nil {
+		t.Fatal(err)
+	}
+	if len(newConfig.RootFS.DiffIDs) != 1 || newConfig.RootFS.DiffIDs[0] != digest.FromString("ordinary diff") {
+		t.Errorf("unexpected diff IDs %v", newConfig.RootFS.DiffIDs)
+	}
+	if newConfig.Author != "test" {
+		t.Errorf("config fields not preserved, author %q", newConfig.Author)
+	}
+
+	// Manifests without non-distributable layers pass through unchanged.
+	again, err := StripNonDistributableLayers(ctx, cs, newDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Digest != newDesc.Digest {
+		t.Error("expected clean manifest to be returned unchanged")
+	}
+}
+
+func TestStripNonDistributableLayersRejectsNonManifest(t *testing.T) {
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = StripNonDistributableLayers(context.Background(), cs, ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+	})
+	if err == nil {
+		t.Fatal("expected error for index descriptor")
+	}
+}
@@ -0,0 +1,41 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"fmt"
+
+	api "github.com/containerd/containerd/v2/api/runtime/task/v3"
+	"github.com/containerd/errdefs"
+)
+
+// ConnectAndVerify connects to a shim and confirms it is actually responsive
+// by issuing a lightweight State request, returning the shim PID. A shim
+// which accepts the connection but fails to answer State is reported as a
+// combined error, which is useful when reconnecting to shims after a
+// containerd restart.
+func ConnectAndVerify(ctx context.Context, client TaskServiceClient, id string) (uint32, error) {
+	resp, err := client.Connect(ctx, &api.ConnectRequest{ID: id})
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to shim for %s: %w", id, errdefs.FromGRPC(err))
+	}
+	if _, err := client.State(ctx, &api.StateRequest{ID: id}); err != nil {
+		return 0, fmt.Errorf("shim for %s (pid %d) connected but not responsive: %w", id, resp.GetShimPid(), errdefs.FromGRPC(err))
+	}
+	return resp.GetShimPid(), nil
+}
@@ -0,0 +1,82 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	api "github.com/containerd/containerd/v2/api/runtime/task/v3"
+)
+
+// mockConnectService extends the terminate mock with Connect/State behavior.
+type mockConnectService struct {
+	TaskServiceClient
+
+	connectErr error
+	stateErr   error
+	shimPid    uint32
+}
+
+func (m *mockConnectService) Connect(ctx context.Context, req *api.ConnectRequest) (*api.ConnectResponse, error) {
+	if m.connectErr != nil {
+		return nil, m.connectErr
+	}
+	return &api.ConnectResponse{ShimPid: m.shimPid}, nil
+}
+
+func (m *mockConnectService) State(ctx context.Context, req *api.StateRequest) (*api.StateResponse, error) {
+	if m.stateErr != nil {
+		return nil, m.stateErr
+	}
+	return &api.StateResponse{ID: req.GetID()}, nil
+}
+
+func TestConnectAndVerify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Responsive", func(t *testing.T) {
+		m := &mockConnectService{shimPid: 1234}
+		pid, err := ConnectAndVerify(ctx, m, "task-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pid != 1234 {
+			t.Errorf("unexpected shim pid %d", pid)
+		}
+	})
+
+	t.Run("ConnectFails", func(t *testing.T) {
+		m := &mockConnectService{connectErr: errors.New("connection refused")}
+		if _, err := ConnectAndVerify(ctx, m, "task-1"); err == nil {
+			t.Fatal("expected error when connect fails")
+		}
+	})
+
+	t.Run("StateFails", func(t *testing.T) {
+		m := &mockConnectService{shimPid: 1234, stateErr: errors.New("deadline exceeded")}
+		_, err := ConnectAndVerify(ctx, m, "task-1")
+		if err == nil {
+			t.Fatal("expected error when state fails")
+		}
+		if !strings.Contains(err.Error(), "pid 1234") || !strings.Contains(err.Error(), "deadline exceeded") {
+			t.Errorf("expected combined error with pid and cause, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"fmt"
+
+	api "github.com/containerd/containerd/v2/api/runtime/task/v3"
+	"github.com/containerd/log"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ExecOpt customizes the exec request issued by ExecAndStart.
+type ExecOpt func(*api.ExecProcessRequest)
+
+// WithExecIO sets the stdio paths and terminal flag for the exec process.
+func WithExecIO(stdin, stdout, stderr string, terminal bool) ExecOpt {
+	return func(req *api.ExecProcessRequest) {
+		req.Stdin = stdin
+		req.Stdout = stdout
+		req.Stderr = stderr
+		req.Terminal = terminal
+	}
+}
+
+// ExecAndStart creates an exec process in the task and starts it, returning
+// the started process PID. If the start fails the exec is deleted so no
+// half-created process is left behind, collapsing the usual
+// Exec/Start/Delete-on-error dance into one call.
+func ExecAndStart(ctx context.Context, client TaskServiceClient, id, execID string, spec *anypb.Any, opts ...ExecOpt) (uint32, error) {
+	req := &api.ExecProcessRequest{
+		ID:     id,
+		ExecID: execID,
+		Spec:   spec,
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+	if _, err := client.Exec(ctx, req); err != nil {
+		return 0, fmt.Errorf("failed to create exec %s: %w", execID, err)
+	}
+
+	resp, err := client.Start(ctx, &api.StartRequest{
+		ID:     id,
+		ExecID: execID,
+	})
+	if err != nil {
+		// Best-effort cleanup, the exec never ran.
+		if _, derr := client.Delete(ctx, &api.DeleteRequest{
+			ID:     id,
+			ExecID: execID,
+		}); derr != nil && !isTaskGone(derr) {
+			log.G(ctx).WithError(derr).WithField("id", id).Errorf("failed to delete exec %s after start failure", execID)
+		}
+		return 0, fmt.Errorf("failed to start exec %s: %w", execID, err)
+	}
+	return resp.GetPid(), nil
+}
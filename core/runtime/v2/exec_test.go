@@ -0,0 +1,91 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	api "github.com/containerd/containerd/v2/api/runtime/task/v3"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// mockExecTaskService implements TaskServiceClient for exec tests, recording
+// the calls received.
+type mockExecTaskService struct {
+	TaskServiceClient
+
+	execReq  *api.ExecProcessRequest
+	startErr error
+	deleted  []string
+}
+
+func (m *mockExecTaskService) Exec(ctx context.Context, req *api.ExecProcessRequest) (*emptypb.Empty, error) {
+	m.execReq = req
+	return &emptypb.Empty{}, nil
+}
+
+func (m *mockExecTaskService) Start(ctx context.Context, req *api.StartRequest) (*api.StartResponse, error) {
+	if m.startErr != nil {
+		return nil, m.startErr
+	}
+	return &api.StartResponse{Pid: 4321}, nil
+}
+
+func (m *mockExecTaskService) Delete(ctx context.Context, req *api.DeleteRequest) (*api.DeleteResponse, error) {
+	m.deleted = append(m.deleted, req.GetExecID())
+	return &api.DeleteResponse{}, nil
+}
+
+func TestExecAndStart(t *testing.T) {
+	m := &mockExecTaskService{}
+
+	pid, err := ExecAndStart(context.Background(), m, "task-1", "exec-1", nil,
+		WithExecIO("/fifo/in", "/fifo/out", "/fifo/err", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid != 4321 {
+		t.Errorf("unexpected pid %d, expected 4321", pid)
+	}
+	if m.execReq == nil {
+		t.Fatal("expected exec request")
+	}
+	if m.execReq.GetID() != "task-1" || m.execReq.GetExecID() != "exec-1" {
+		t.Errorf("unexpected exec request %+v", m.execReq)
+	}
+	if m.execReq.GetStdout() != "/fifo/out" || !m.execReq.GetTerminal() {
+		t.Errorf("exec IO options not applied: %+v", m.execReq)
+	}
+	if len(m.deleted) != 0 {
+		t.Errorf("unexpected deletes %v", m.deleted)
+	}
+}
+
+func TestExecAndStartCleansUpOnStartFailure(t *testing.T) {
+	errStart := errors.New("start failed")
+	m := &mockExecTaskService{startErr: errStart}
+
+	_, err := ExecAndStart(context.Background(), m, "task-1", "exec-1", nil)
+	if !errors.Is(err, errStart) {
+		t.Fatalf("expected start error, got %v", err)
+	}
+	if len(m.deleted) != 1 || m.deleted[0] != "exec-1" {
+		t.Errorf("expected exec-1 to be deleted, got %v", m.deleted)
+	}
+}
@@ -0,0 +1,206 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/containerd/ttrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	api "github.com/containerd/containerd/v2/api/runtime/task/v3"
+)
+
+// NewTaskClientWithRetry returns a task client which retries transient
+// connection errors, such as a connection refused while the shim socket is
+// still becoming ready right after shim start. Application-level errors
+// (e.g. NotFound) are returned immediately. Each method makes at most
+// attempts calls, sleeping backoff between them, and respects context
+// cancellation while waiting.
+func NewTaskClientWithRetry(client interface{}, version int, attempts int, backoff time.Duration) (TaskServiceClient, error) {
+	if attempts < 1 {
+		return nil, fmt.Errorf("retry attempts must be at least 1 (got %d)", attempts)
+	}
+	if backoff < 0 {
+		return nil, fmt.Errorf("retry backoff must not be negative (got %s)", backoff)
+	}
+	tc, err := NewTaskClient(client, version)
+	if err != nil {
+		return nil, err
+	}
+	return &retryTaskClient{
+		client:   tc,
+		attempts: attempts,
+		backoff:  backoff,
+	}, nil
+}
+
+// retryTaskClient wraps a TaskServiceClient, retrying each method on
+// transient connection errors.
+type retryTaskClient struct {
+	client   TaskServiceClient
+	attempts int
+	backoff  time.Duration
+}
+
+var _ TaskServiceClient = (*retryTaskClient)(nil)
+
+// isTransientConnError reports whether err looks like a connection-level
+// failure worth retrying, rather than an error returned by the shim itself.
+func isTransientConnError(err error) bool {
+	if errors.Is(err, ttrpc.ErrClosed) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ENOENT) {
+		return true
+	}
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Unavailable {
+		return true
+	}
+	return false
+}
+
+// withRetry calls fn up to attempts times, sleeping backoff between calls
+// which failed with a transient connection error.
+func withRetry[T any](ctx context.Context, attempts int, backoff time.Duration, fn func() (T, error)) (T, error) {
+	var (
+		resp T
+		err  error
+	)
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return resp, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+		resp, err = fn()
+		if err == nil || !isTransientConnError(err) {
+			return resp, err
+		}
+	}
+	return resp, err
+}
+
+func (r *retryTaskClient) State(ctx context.Context, req *api.StateRequest) (*api.StateResponse, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*api.StateResponse, error) {
+		return r.client.State(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Create(ctx context.Context, req *api.CreateTaskRequest) (*api.CreateTaskResponse, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*api.CreateTaskResponse, error) {
+		return r.client.Create(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Start(ctx context.Context, req *api.StartRequest) (*api.StartResponse, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*api.StartResponse, error) {
+		return r.client.Start(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Delete(ctx context.Context, req *api.DeleteRequest) (*api.DeleteResponse, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*api.DeleteResponse, error) {
+		return r.client.Delete(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Pids(ctx context.Context, req *api.PidsRequest) (*api.PidsResponse, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*api.PidsResponse, error) {
+		return r.client.Pids(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Pause(ctx context.Context, req *api.PauseRequest) (*emptypb.Empty, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*emptypb.Empty, error) {
+		return r.client.Pause(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Resume(ctx context.Context, req *api.ResumeRequest) (*emptypb.Empty, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*emptypb.Empty, error) {
+		return r.client.Resume(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Checkpoint(ctx context.Context, req *api.CheckpointTaskRequest) (*emptypb.Empty, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*emptypb.Empty, error) {
+		return r.client.Checkpoint(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Kill(ctx context.Context, req *api.KillRequest) (*emptypb.Empty, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*emptypb.Empty, error) {
+		return r.client.Kill(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Exec(ctx context.Context, req *api.ExecProcessRequest) (*emptypb.Empty, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*emptypb.Empty, error) {
+		return r.client.Exec(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) ResizePty(ctx context.Context, req *api.ResizePtyRequest) (*emptypb.Empty, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*emptypb.Empty, error) {
+		return r.client.ResizePty(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) CloseIO(ctx context.Context, req *api.CloseIORequest) (*emptypb.Empty, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*emptypb.Empty, error) {
+		return r.client.CloseIO(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Update(ctx context.Context, req *api.UpdateTaskRequest) (*emptypb.Empty, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*emptypb.Empty, error) {
+		return r.client.Update(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Wait(ctx context.Context, req *api.WaitRequest) (*api.WaitResponse, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*api.WaitResponse, error) {
+		return r.client.Wait(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Stats(ctx context.Context, req *api.StatsRequest) (*api.StatsResponse, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*api.StatsResponse, error) {
+		return r.client.Stats(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Connect(ctx context.Context, req *api.ConnectRequest) (*api.ConnectResponse, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*api.ConnectResponse, error) {
+		return r.client.Connect(ctx, req)
+	})
+}
+
+func (r *retryTaskClient) Shutdown(ctx context.Context, req *api.ShutdownRequest) (*emptypb.Empty, error) {
+	return withRetry(ctx, r.attempts, r.backoff, func() (*emptypb.Empty, error) {
+		return r.client.Shutdown(ctx, req)
+	})
+}
@@ -0,0 +1,109 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	api "github.com/containerd/containerd/v2/api/runtime/task/v3"
+)
+
+// flakyConnectService fails Connect with the configured error until failures
+// runs out, then succeeds.
+type flakyConnectService struct {
+	TaskServiceClient
+
+	err      error
+	failures int
+	calls    int
+}
+
+func (m *flakyConnectService) Connect(ctx context.Context, req *api.ConnectRequest) (*api.ConnectResponse, error) {
+	m.calls++
+	if m.calls <= m.failures {
+		return nil, m.err
+	}
+	return &api.ConnectResponse{ShimPid: 42}, nil
+}
+
+func TestTaskClientRetry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("TransientErrorRetried", func(t *testing.T) {
+		m := &flakyConnectService{err: syscall.ECONNREFUSED, failures: 2}
+		c := &retryTaskClient{client: m, attempts: 3, backoff: time.Millisecond}
+		resp, err := c.Connect(ctx, &api.ConnectRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetShimPid() != 42 {
+			t.Errorf("unexpected shim pid %d", resp.GetShimPid())
+		}
+		if m.calls != 3 {
+			t.Errorf("expected 3 calls, got %d", m.calls)
+		}
+	})
+
+	t.Run("AttemptsExhausted", func(t *testing.T) {
+		m := &flakyConnectService{err: syscall.ECONNREFUSED, failures: 5}
+		c := &retryTaskClient{client: m, attempts: 2, backoff: time.Millisecond}
+		if _, err := c.Connect(ctx, &api.ConnectRequest{}); !errors.Is(err, syscall.ECONNREFUSED) {
+			t.Fatalf("expected connection refused after retries, got %v", err)
+		}
+		if m.calls != 2 {
+			t.Errorf("expected 2 calls, got %d", m.calls)
+		}
+	})
+
+	t.Run("ApplicationErrorNotRetried", func(t *testing.T) {
+		appErr := errors.New("task not found")
+		m := &flakyConnectService{err: appErr, failures: 5}
+		c := &retryTaskClient{client: m, attempts: 3, backoff: time.Millisecond}
+		if _, err := c.Connect(ctx, &api.ConnectRequest{}); !errors.Is(err, appErr) {
+			t.Fatalf("expected application error, got %v", err)
+		}
+		if m.calls != 1 {
+			t.Errorf("expected 1 call, got %d", m.calls)
+		}
+	})
+
+	t.Run("ContextCanceledBetweenRetries", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		m := &flakyConnectService{err: syscall.ECONNREFUSED, failures: 5}
+		c := &retryTaskClient{client: m, attempts: 3, backoff: time.Minute}
+		if _, err := c.Connect(cctx, &api.ConnectRequest{}); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if m.calls != 1 {
+			t.Errorf("expected 1 call before cancellation, got %d", m.calls)
+		}
+	})
+}
+
+func TestNewTaskClientWithRetryValidation(t *testing.T) {
+	if _, err := NewTaskClientWithRetry(nil, 3, 0, time.Second); err == nil {
+		t.Error("expected error for zero attempts")
+	}
+	if _, err := NewTaskClientWithRetry(nil, 3, 1, -time.Second); err == nil {
+		t.Error("expected error for negative backoff")
+	}
+}
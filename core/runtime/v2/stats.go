@@ -0,0 +1,78 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	api "github.com/containerd/containerd/v2/api/runtime/task/v3"
+)
+
+// StatsStream polls the task's Stats on the given interval, delivering each
+// response on the returned stats channel. Polling stops on context
+// cancellation or a non-retryable error, at which point the terminal error is
+// delivered on the error channel and both channels are closed. Transient
+// connection errors are skipped and polling continues on the next tick.
+func StatsStream(ctx context.Context, client TaskServiceClient, id string, interval time.Duration) (<-chan *api.StatsResponse, <-chan error) {
+	statsCh := make(chan *api.StatsResponse)
+	errCh := make(chan error, 1)
+
+	if interval <= 0 {
+		errCh <- fmt.Errorf("stats interval must be positive (got %s)", interval)
+		close(statsCh)
+		close(errCh)
+		return statsCh, errCh
+	}
+
+	go func() {
+		defer close(statsCh)
+		defer close(errCh)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
+			case <-ticker.C:
+			}
+
+			resp, err := client.Stats(ctx, &api.StatsRequest{ID: id})
+			if err != nil {
+				if isTransientConnError(err) {
+					// The shim may be briefly unreachable, sample
+					// again on the next tick.
+					continue
+				}
+				errCh <- err
+				return
+			}
+
+			select {
+			case statsCh <- resp:
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return statsCh, errCh
+}
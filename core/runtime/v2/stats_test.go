@@ -0,0 +1,103 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	api "github.com/containerd/containerd/v2/api/runtime/task/v3"
+)
+
+// statsTaskService implements TaskServiceClient for stats polling tests,
+// counting Stats calls and optionally failing them.
+type statsTaskService struct {
+	TaskServiceClient
+
+	calls    atomic.Int32
+	statsErr error
+}
+
+func (m *statsTaskService) Stats(ctx context.Context, req *api.StatsRequest) (*api.StatsResponse, error) {
+	m.calls.Add(1)
+	if m.statsErr != nil {
+		return nil, m.statsErr
+	}
+	return &api.StatsResponse{}, nil
+}
+
+func TestStatsStream(t *testing.T) {
+	m := &statsTaskService{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interval := 10 * time.Millisecond
+	statsCh, errCh := StatsStream(ctx, m, "task-1", interval)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		select {
+		case resp := <-statsCh:
+			if resp == nil {
+				t.Fatal("unexpected nil stats response")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for stats sample")
+		}
+	}
+	// Three samples cannot arrive faster than the ticks driving them.
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Errorf("samples arrived too fast: 3 in %s with interval %s", elapsed, interval)
+	}
+
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, ok := <-statsCh; ok {
+		t.Error("expected stats channel to be closed")
+	}
+}
+
+func TestStatsStreamTerminalError(t *testing.T) {
+	statsErr := errors.New("task not created")
+	m := &statsTaskService{statsErr: statsErr}
+	ctx := context.Background()
+
+	statsCh, errCh := StatsStream(ctx, m, "task-1", time.Millisecond)
+
+	if err := <-errCh; !errors.Is(err, statsErr) {
+		t.Errorf("expected stats error, got %v", err)
+	}
+	if _, ok := <-statsCh; ok {
+		t.Error("expected stats channel to be closed")
+	}
+	if got := m.calls.Load(); got != 1 {
+		t.Errorf("expected polling to stop after the first error, got %d calls", got)
+	}
+}
+
+func TestStatsStreamInvalidInterval(t *testing.T) {
+	m := &statsTaskService{}
+	_, errCh := StatsStream(context.Background(), m, "task-1", 0)
+	if err := <-errCh; err == nil {
+		t.Fatal("expected error for non-positive interval")
+	}
+}
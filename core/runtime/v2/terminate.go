@@ -0,0 +1,86 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	api "github.com/containerd/containerd/v2/api/runtime/task/v3"
+	"github.com/containerd/errdefs"
+)
+
+// isTaskGone reports whether a shim error indicates the task no longer
+// exists, accepting both native and GRPC-transported errors.
+func isTaskGone(err error) bool {
+	return errdefs.IsNotFound(err) || errdefs.IsNotFound(errdefs.FromGRPC(err))
+}
+
+// TerminateTask stops a task by sending SIGTERM, waiting up to grace for it
+// to exit, and escalating to SIGKILL if it has not. The final wait result is
+// returned. A task which is already gone returns a nil response without
+// error.
+func TerminateTask(ctx context.Context, client TaskServiceClient, id string, grace time.Duration) (*api.WaitResponse, error) {
+	_, err := client.Kill(ctx, &api.KillRequest{
+		ID:     id,
+		Signal: uint32(syscall.SIGTERM),
+	})
+	if err != nil {
+		if isTaskGone(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	type waitResult struct {
+		resp *api.WaitResponse
+		err  error
+	}
+	waitCh := make(chan waitResult, 1)
+	go func() {
+		resp, err := client.Wait(ctx, &api.WaitRequest{ID: id})
+		waitCh <- waitResult{resp, err}
+	}()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case r := <-waitCh:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
+
+	// Grace period expired, escalate to SIGKILL. The task may have exited
+	// in the meantime, so a missing task is not an error.
+	_, err = client.Kill(ctx, &api.KillRequest{
+		ID:     id,
+		Signal: uint32(syscall.SIGKILL),
+	})
+	if err != nil && !isTaskGone(err) {
+		return nil, err
+	}
+
+	select {
+	case r := <-waitCh:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
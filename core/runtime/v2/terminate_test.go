@@ -0,0 +1,118 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	api "github.com/containerd/containerd/v2/api/runtime/task/v3"
+	"github.com/containerd/errdefs"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// mockTaskServiceV2 implements TaskServiceClient for terminate tests. Kill
+// records received signals and Wait blocks until released.
+type mockTaskServiceV2 struct {
+	TaskServiceClient
+
+	killErr error
+	signals chan uint32
+	exited  chan struct{}
+}
+
+func newMockTaskServiceV2() *mockTaskServiceV2 {
+	return &mockTaskServiceV2{
+		signals: make(chan uint32, 10),
+		exited:  make(chan struct{}),
+	}
+}
+
+func (m *mockTaskServiceV2) Kill(ctx context.Context, req *api.KillRequest) (*emptypb.Empty, error) {
+	if m.killErr != nil {
+		return nil, m.killErr
+	}
+	m.signals <- req.GetSignal()
+	return &emptypb.Empty{}, nil
+}
+
+func (m *mockTaskServiceV2) Wait(ctx context.Context, req *api.WaitRequest) (*api.WaitResponse, error) {
+	select {
+	case <-m.exited:
+		return &api.WaitResponse{ExitStatus: 137}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestTerminateTaskEscalatesToSigkill(t *testing.T) {
+	m := newMockTaskServiceV2()
+
+	// Release the wait only once SIGKILL has been observed.
+	go func() {
+		for sig := range m.signals {
+			if sig == uint32(syscall.SIGKILL) {
+				close(m.exited)
+				return
+			}
+		}
+	}()
+
+	resp, err := TerminateTask(context.Background(), m, "task-1", 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetExitStatus() != 137 {
+		t.Errorf("unexpected exit status %d, expected 137", resp.GetExitStatus())
+	}
+}
+
+func TestTerminateTaskGracefulExit(t *testing.T) {
+	m := newMockTaskServiceV2()
+	close(m.exited)
+
+	resp, err := TerminateTask(context.Background(), m, "task-1", 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil {
+		t.Fatal("expected wait response")
+	}
+	if sig := <-m.signals; sig != uint32(syscall.SIGTERM) {
+		t.Errorf("unexpected first signal %d, expected SIGTERM", sig)
+	}
+	select {
+	case sig := <-m.signals:
+		t.Errorf("unexpected extra signal %d", sig)
+	default:
+	}
+}
+
+func TestTerminateTaskAlreadyGone(t *testing.T) {
+	m := newMockTaskServiceV2()
+	m.killErr = errdefs.ErrNotFound
+
+	resp, err := TerminateTask(context.Background(), m, "task-1", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil {
+		t.Errorf("expected nil response for missing task, got %v", resp)
+	}
+}
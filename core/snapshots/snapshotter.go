@@ -18,13 +18,18 @@ package snapshots
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	snapshotsapi "github.com/containerd/containerd/v2/api/services/snapshots/v1"
 	"github.com/containerd/containerd/v2/core/mount"
 	"github.com/containerd/containerd/v2/protobuf"
+	"github.com/containerd/errdefs"
 )
 
 const (
@@ -84,6 +89,33 @@ func (k Kind) String() string {
 	}
 }
 
+// IsView returns true when the kind is KindView.
+func (k Kind) IsView() bool {
+	return k == KindView
+}
+
+// IsActive returns true when the kind is KindActive.
+func (k Kind) IsActive() bool {
+	return k == KindActive
+}
+
+// IsCommitted returns true when the kind is KindCommitted.
+func (k Kind) IsCommitted() bool {
+	return k == KindCommitted
+}
+
+// IsValid returns true for any known kind, and false for KindUnknown, so
+// call sites cannot accidentally treat an unknown kind as one of the valid
+// states.
+func (k Kind) IsValid() bool {
+	switch k {
+	case KindView, KindActive, KindCommitted:
+		return true
+	default:
+		return false
+	}
+}
+
 // MarshalJSON the Kind to JSON
 func (k Kind) MarshalJSON() ([]byte, error) {
 	return json.Marshal(k.String())
@@ -141,6 +173,67 @@ type Info struct {
 	Updated time.Time         `json:",omitempty"` // Last update time
 }
 
+// Clone returns a copy of the info with its own Labels map, so the copy can
+// be mutated without affecting the original. Labels maps are otherwise shared
+// by value copies of Info, which makes `info := existing` followed by a label
+// write an easy source of aliasing bugs.
+func (i Info) Clone() Info {
+	if i.Labels != nil {
+		labels := make(map[string]string, len(i.Labels))
+		for k, v := range i.Labels {
+			labels[k] = v
+		}
+		i.Labels = labels
+	}
+	return i
+}
+
+// inlineLabelLimit is the maximum number of labels rendered inline by
+// Info.String before falling back to a count.
+const inlineLabelLimit = 3
+
+// String renders a compact single-line form of the snapshot info suitable
+// for logging. Large label maps are summarized by their count rather than
+// dumped inline, and values of sensitive-looking labels are redacted.
+func (i Info) String() string {
+	parts := make([]string, 0, 4)
+	parts = append(parts, "name="+i.Name)
+	if i.Parent != "" {
+		parts = append(parts, "parent="+i.Parent)
+	}
+	parts = append(parts, "kind="+i.Kind.String())
+	if n := len(i.Labels); n > 0 && n <= inlineLabelLimit {
+		keys := make([]string, 0, n)
+		for k := range i.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for idx, k := range keys {
+			if isSensitiveLabel(k) {
+				keys[idx] = k + "=<redacted>"
+			} else {
+				keys[idx] = k + "=" + i.Labels[k]
+			}
+		}
+		parts = append(parts, "labels="+strings.Join(keys, ","))
+	} else {
+		parts = append(parts, fmt.Sprintf("labels=%d", n))
+	}
+	return strings.Join(parts, " ")
+}
+
+// isSensitiveLabel reports whether a label key looks like it may carry a
+// credential and should not have its value logged.
+func isSensitiveLabel(k string) bool {
+	k = strings.ToLower(k)
+	for _, s := range []string{"secret", "token", "password", "credential", "auth"} {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
 // InfoToProto converts from [Info] to the protobuf definition [snapshots.Info].
 func InfoToProto(info Info) *snapshotsapi.Info {
 	return &snapshotsapi.Info{
@@ -433,16 +526,19 @@ type Cleaner interface {
 // Opt allows setting mutable snapshot properties on creation
 type Opt func(info *Info) error
 
-// WithLabels appends labels to a created snapshot
+// WithLabels appends labels to a created snapshot. The info's existing map
+// is never written to in place; a fresh map is allocated so that label maps
+// shared with a caller remain untouched.
 func WithLabels(labels map[string]string) Opt {
 	return func(info *Info) error {
-		if info.Labels == nil {
-			info.Labels = make(map[string]string)
+		merged := make(map[string]string, len(info.Labels)+len(labels))
+		for k, v := range info.Labels {
+			merged[k] = v
 		}
-
 		for k, v := range labels {
-			info.Labels[k] = v
+			merged[k] = v
 		}
+		info.Labels = merged
 
 		return nil
 	}
@@ -464,3 +560,181 @@ func FilterInheritedLabels(labels map[string]string) map[string]string {
 	}
 	return filtered
 }
+
+// UpdateLabels applies label additions and removals to a snapshot in one
+// step, reusing the merge semantics of WithLabels and committing only the
+// "labels" fieldpath so other Info fields are left untouched. Note the
+// read-modify-write is only as atomic as the snapshotter's Update; callers
+// racing on the same key should serialize externally.
+func UpdateLabels(ctx context.Context, sn Snapshotter, key string, add map[string]string, remove []string) (Info, error) {
+	info, err := sn.Stat(ctx, key)
+	if err != nil {
+		return Info{}, err
+	}
+	if err := WithLabels(add)(&info); err != nil {
+		return Info{}, err
+	}
+	for _, k := range remove {
+		delete(info.Labels, k)
+	}
+	return sn.Update(ctx, info, "labels")
+}
+
+// WalkByLabel walks all snapshots and invokes fn only for those whose labels
+// contain key with the given value. The filtering happens client-side, so it
+// works against any snapshotter regardless of its filter-string support.
+func WalkByLabel(ctx context.Context, sn Snapshotter, key, value string, fn func(Info) error) error {
+	return sn.Walk(ctx, func(ctx context.Context, info Info) error {
+		if info.Labels[key] != value {
+			return nil
+		}
+		return fn(info)
+	})
+}
+
+// Validate checks that an Info is well-formed before it is handed to a
+// snapshotter, so malformed values are rejected up front rather than deep
+// inside a storage transaction.
+func (i Info) Validate() error {
+	if i.Name == "" {
+		return fmt.Errorf("snapshot name must not be empty: %w", errdefs.ErrInvalidArgument)
+	}
+	if !i.Kind.IsValid() {
+		return fmt.Errorf("snapshot %q has invalid kind %q: %w", i.Name, i.Kind, errdefs.ErrInvalidArgument)
+	}
+	if (i.Kind.IsView() || i.Kind.IsActive()) && i.Parent == i.Name {
+		return fmt.Errorf("snapshot %q must not be its own parent: %w", i.Name, errdefs.ErrInvalidArgument)
+	}
+	return nil
+}
+
+// ParentChain returns the ordered ancestor keys of the given snapshot,
+// starting with key itself and following Info.Parent until the root. A
+// missing link surfaces the snapshotter's NotFound error; a parent cycle is
+// rejected rather than followed forever.
+func ParentChain(ctx context.Context, sn Snapshotter, key string) ([]string, error) {
+	var (
+		chain   []string
+		visited = map[string]struct{}{}
+	)
+	for key != "" {
+		if _, ok := visited[key]; ok {
+			return nil, fmt.Errorf("snapshot parent chain contains a cycle at %q: %w", key, errdefs.ErrInvalidArgument)
+		}
+		visited[key] = struct{}{}
+		info, err := sn.Stat(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, key)
+		key = info.Parent
+	}
+	return chain, nil
+}
+
+// Leaves returns the snapshots which are nobody's parent, i.e. the leaves of
+// the snapshot forest, in a single Walk pass. These are the snapshots which
+// can be removed without invalidating a child. Filters narrow the set walked;
+// a parent excluded by a filter does not hide its children from the result.
+func Leaves(ctx context.Context, sn Snapshotter, filters ...string) ([]Info, error) {
+	var (
+		infos   []Info
+		parents = map[string]struct{}{}
+	)
+	if err := sn.Walk(ctx, func(ctx context.Context, info Info) error {
+		infos = append(infos, info)
+		if info.Parent != "" {
+			parents[info.Parent] = struct{}{}
+		}
+		return nil
+	}, filters...); err != nil {
+		return nil, err
+	}
+
+	var leaves []Info
+	for _, info := range infos {
+		if _, ok := parents[info.Name]; !ok {
+			leaves = append(leaves, info)
+		}
+	}
+	return leaves, nil
+}
+
+// Reclaimable estimates how much space removing the given snapshots would
+// free: the summed usage of every snapshot which would become unreferenced,
+// excluding anything still reachable from a retained snapshot. Removing a
+// parent still referenced by a retained child therefore frees nothing for
+// it. The estimate inherits the accuracy of the snapshotter's Usage.
+func Reclaimable(ctx context.Context, sn Snapshotter, keys []string) (Usage, error) {
+	remove := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		remove[k] = struct{}{}
+	}
+
+	parents := map[string]string{}
+	if err := sn.Walk(ctx, func(ctx context.Context, info Info) error {
+		parents[info.Name] = info.Parent
+		return nil
+	}); err != nil {
+		return Usage{}, err
+	}
+	for _, k := range keys {
+		if _, ok := parents[k]; !ok {
+			return Usage{}, fmt.Errorf("snapshot %q: %w", k, errdefs.ErrNotFound)
+		}
+	}
+
+	// Everything reachable from a retained snapshot stays.
+	keep := map[string]struct{}{}
+	for name := range parents {
+		if _, rm := remove[name]; rm {
+			continue
+		}
+		for n := name; n != ""; n = parents[n] {
+			if _, ok := keep[n]; ok {
+				break
+			}
+			keep[n] = struct{}{}
+		}
+	}
+
+	var total Usage
+	for name := range parents {
+		if _, kept := keep[name]; kept {
+			continue
+		}
+		u, err := sn.Usage(ctx, name)
+		if err != nil {
+			return Usage{}, err
+		}
+		total.Add(u)
+	}
+	return total, nil
+}
+
+// WithView creates a uniquely-keyed read-only view of the given parent
+// snapshot, invokes fn with its mounts, and removes the view when fn
+// returns — also on error or panic — so inspection tooling cannot leak
+// views. The error from fn is returned; a failure to remove the view is
+// surfaced only when fn itself succeeded.
+func WithView(ctx context.Context, sn Snapshotter, parent string, fn func(mounts []mount.Mount) error) (retErr error) {
+	key := fmt.Sprintf("%s-view-%s", parent, uniquePart())
+	mounts, err := sn.View(ctx, key, parent)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := sn.Remove(ctx, key); rerr != nil && retErr == nil {
+			retErr = fmt.Errorf("failed to remove view %q: %w", key, rerr)
+		}
+	}()
+	return fn(mounts)
+}
+
+func uniquePart() string {
+	t := time.Now()
+	var b [3]byte
+	// Ignore read failures, just decreases uniqueness
+	rand.Read(b[:])
+	return fmt.Sprintf("%d-%s", t.Nanosecond(), base64.URLEncoding.EncodeToString(b[:]))
+}
@@ -0,0 +1,535 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snapshots
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/mount"
+	"github.com/containerd/errdefs"
+)
+
+func TestInfoString(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		info     Info
+		expected string
+	}{
+		{
+			name:     "Empty",
+			info:     Info{},
+			expected: "name= kind=Unknown labels=0",
+		},
+		{
+			name: "Populated",
+			info: Info{
+				Kind:   KindActive,
+				Name:   "sha256:abc",
+				Parent: "sha256:def",
+				Labels: map[string]string{
+					"containerd.io/gc.root": "2024-01-01",
+				},
+			},
+			expected: "name=sha256:abc parent=sha256:def kind=Active labels=containerd.io/gc.root=2024-01-01",
+		},
+		{
+			name: "ManyLabels",
+			info: Info{
+				Kind: KindCommitted,
+				Name: "sha256:abc",
+				Labels: map[string]string{
+					"a": "1", "b": "2", "c": "3", "d": "4",
+				},
+			},
+			expected: "name=sha256:abc kind=Committed labels=4",
+		},
+		{
+			name: "SensitiveLabelRedacted",
+			info: Info{
+				Kind: KindView,
+				Name: "view-1",
+				Labels: map[string]string{
+					"registry-token": "s3cret",
+				},
+			},
+			expected: "name=view-1 kind=View labels=registry-token=<redacted>",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if s := tc.info.String(); s != tc.expected {
+				t.Errorf("unexpected string %q, expected %q", s, tc.expected)
+			}
+		})
+	}
+}
+
+func TestInfoClone(t *testing.T) {
+	original := Info{
+		Kind: KindCommitted,
+		Name: "sha256:abc",
+		Labels: map[string]string{
+			"containerd.io/gc.root": "2024-01-01",
+		},
+	}
+
+	clone := original.Clone()
+	clone.Labels["containerd.io/gc.root"] = "mutated"
+	clone.Labels["extra"] = "value"
+
+	if got := original.Labels["containerd.io/gc.root"]; got != "2024-01-01" {
+		t.Errorf("original label mutated through clone: %q", got)
+	}
+	if _, ok := original.Labels["extra"]; ok {
+		t.Error("label added to clone leaked into original")
+	}
+
+	if clone := (Info{}).Clone(); clone.Labels != nil {
+		t.Error("clone of label-less info should keep nil Labels")
+	}
+}
+
+func TestWithLabelsDoesNotMutateSharedMap(t *testing.T) {
+	shared := map[string]string{"existing": "1"}
+	info := Info{Labels: shared}
+
+	if err := WithLabels(map[string]string{"new": "2"})(&info); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := shared["new"]; ok {
+		t.Error("WithLabels wrote into the shared map")
+	}
+	if info.Labels["existing"] != "1" || info.Labels["new"] != "2" {
+		t.Errorf("unexpected merged labels %v", info.Labels)
+	}
+}
+
+func TestKindPredicates(t *testing.T) {
+	for _, tc := range []struct {
+		kind      Kind
+		view      bool
+		active    bool
+		committed bool
+		valid     bool
+	}{
+		{kind: KindUnknown},
+		{kind: KindView, view: true, valid: true},
+		{kind: KindActive, active: true, valid: true},
+		{kind: KindCommitted, committed: true, valid: true},
+	} {
+		t.Run(tc.kind.String(), func(t *testing.T) {
+			if got := tc.kind.IsView(); got != tc.view {
+				t.Errorf("IsView() = %v, expected %v", got, tc.view)
+			}
+			if got := tc.kind.IsActive(); got != tc.active {
+				t.Errorf("IsActive() = %v, expected %v", got, tc.active)
+			}
+			if got := tc.kind.IsCommitted(); got != tc.committed {
+				t.Errorf("IsCommitted() = %v, expected %v", got, tc.committed)
+			}
+			if got := tc.kind.IsValid(); got != tc.valid {
+				t.Errorf("IsValid() = %v, expected %v", got, tc.valid)
+			}
+		})
+	}
+}
+
+// walkSnapshotter is a mock snapshotter which walks a fixed set of snapshots
+// and ignores filter strings.
+type walkSnapshotter struct {
+	Snapshotter
+	infos []Info
+}
+
+func (w *walkSnapshotter) Walk(ctx context.Context, fn WalkFunc, filters ...string) error {
+	for _, info := range w.infos {
+		if err := fn(ctx, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestWalkByLabel(t *testing.T) {
+	ctx := context.Background()
+	sn := &walkSnapshotter{
+		infos: []Info{
+			{Name: "matching", Labels: map[string]string{"env": "prod"}},
+			{Name: "other-value", Labels: map[string]string{"env": "dev"}},
+			{Name: "no-labels"},
+			{Name: "matching-too", Labels: map[string]string{"env": "prod", "extra": "1"}},
+		},
+	}
+
+	var visited []string
+	err := WalkByLabel(ctx, sn, "env", "prod", func(info Info) error {
+		visited = append(visited, info.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(visited) != 2 || visited[0] != "matching" || visited[1] != "matching-too" {
+		t.Errorf("unexpected snapshots visited: %v", visited)
+	}
+
+	errWalk := errors.New("stop walk")
+	err = WalkByLabel(ctx, sn, "env", "prod", func(Info) error {
+		return errWalk
+	})
+	if !errors.Is(err, errWalk) {
+		t.Errorf("expected callback error to propagate, got %v", err)
+	}
+}
+
+func TestInfoValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		info  Info
+		valid bool
+	}{
+		{
+			name:  "Valid",
+			info:  Info{Name: "snap-1", Kind: KindActive, Parent: "base"},
+			valid: true,
+		},
+		{
+			name:  "ValidCommitted",
+			info:  Info{Name: "base", Kind: KindCommitted},
+			valid: true,
+		},
+		{
+			name: "EmptyName",
+			info: Info{Kind: KindActive},
+		},
+		{
+			name: "UnknownKind",
+			info: Info{Name: "snap-1"},
+		},
+		{
+			name: "SelfParent",
+			info: Info{Name: "snap-1", Kind: KindView, Parent: "snap-1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.info.Validate()
+			if tc.valid {
+				if err != nil {
+					t.Fatalf("expected valid info, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			if !errdefs.IsInvalidArgument(err) {
+				t.Errorf("expected invalid argument error, got %v", err)
+			}
+		})
+	}
+}
+
+// statSnapshotter is a mock whose Stat serves from a fixed set of infos.
+type statSnapshotter struct {
+	Snapshotter
+
+	infos map[string]Info
+}
+
+func (s *statSnapshotter) Stat(ctx context.Context, key string) (Info, error) {
+	info, ok := s.infos[key]
+	if !ok {
+		return Info{}, errdefs.ErrNotFound
+	}
+	return info, nil
+}
+
+func TestParentChain(t *testing.T) {
+	ctx := context.Background()
+	sn := &statSnapshotter{infos: map[string]Info{
+		"leaf":   {Name: "leaf", Parent: "middle", Kind: KindCommitted},
+		"middle": {Name: "middle", Parent: "root", Kind: KindCommitted},
+		"root":   {Name: "root", Kind: KindCommitted},
+		"broken": {Name: "broken", Parent: "missing", Kind: KindCommitted},
+		"loop-a": {Name: "loop-a", Parent: "loop-b", Kind: KindCommitted},
+		"loop-b": {Name: "loop-b", Parent: "loop-a", Kind: KindCommitted},
+	}}
+
+	chain, err := ParentChain(ctx, sn, "leaf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"leaf", "middle", "root"}
+	if len(chain) != len(expected) {
+		t.Fatalf("expected chain %v, got %v", expected, chain)
+	}
+	for i := range expected {
+		if chain[i] != expected[i] {
+			t.Errorf("expected %q at position %d, got %q", expected[i], i, chain[i])
+		}
+	}
+
+	if _, err := ParentChain(ctx, sn, "broken"); !errdefs.IsNotFound(err) {
+		t.Errorf("expected not found for broken link, got %v", err)
+	}
+
+	if _, err := ParentChain(ctx, sn, "loop-a"); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument for parent cycle, got %v", err)
+	}
+}
+
+func TestLeaves(t *testing.T) {
+	ctx := context.Background()
+
+	// A forest with two trees:
+	//   root -> middle -> leaf-a
+	//                  -> leaf-b
+	//   solo (no children)
+	sn := &walkSnapshotter{infos: []Info{
+		{Name: "root", Kind: KindCommitted},
+		{Name: "middle", Parent: "root", Kind: KindCommitted},
+		{Name: "leaf-a", Parent: "middle", Kind: KindActive},
+		{Name: "leaf-b", Parent: "middle", Kind: KindCommitted},
+		{Name: "solo", Kind: KindCommitted},
+	}}
+
+	leaves, err := Leaves(ctx, sn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]bool{"leaf-a": true, "leaf-b": true, "solo": true}
+	if len(leaves) != len(expected) {
+		t.Fatalf("expected %d leaves, got %v", len(expected), leaves)
+	}
+	for _, l := range leaves {
+		if !expected[l.Name] {
+			t.Errorf("unexpected leaf %q", l.Name)
+		}
+	}
+}
+
+// viewSnapshotter is a mock recording view creation and removal.
+type viewSnapshotter struct {
+	Snapshotter
+
+	viewErr error
+	views   map[string]string
+	removed []string
+}
+
+func (v *viewSnapshotter) View(ctx context.Context, key, parent string, opts ...Opt) ([]mount.Mount, error) {
+	if v.viewErr != nil {
+		return nil, v.viewErr
+	}
+	if v.views == nil {
+		v.views = map[string]string{}
+	}
+	v.views[key] = parent
+	return []mount.Mount{{Type: "bind", Source: "/snapshots/" + key}}, nil
+}
+
+func (v *viewSnapshotter) Remove(ctx context.Context, key string) error {
+	if _, ok := v.views[key]; !ok {
+		return errdefs.ErrNotFound
+	}
+	delete(v.views, key)
+	v.removed = append(v.removed, key)
+	return nil
+}
+
+func TestWithView(t *testing.T) {
+	ctx := context.Background()
+	sn := &viewSnapshotter{}
+
+	var got []mount.Mount
+	err := WithView(ctx, sn, "base", func(mounts []mount.Mount) error {
+		got = mounts
+		if len(sn.views) != 1 {
+			t.Errorf("expected 1 live view during fn, got %d", len(sn.views))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Type != "bind" {
+		t.Errorf("unexpected mounts %v", got)
+	}
+	if len(sn.views) != 0 || len(sn.removed) != 1 {
+		t.Errorf("view not removed: live=%v removed=%v", sn.views, sn.removed)
+	}
+
+	// The view is removed even when fn errors, and the error propagates.
+	errFn := errors.New("inspection failed")
+	err = WithView(ctx, sn, "base", func([]mount.Mount) error {
+		return errFn
+	})
+	if !errors.Is(err, errFn) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+	if len(sn.views) != 0 || len(sn.removed) != 2 {
+		t.Errorf("view not removed after error: live=%v removed=%v", sn.views, sn.removed)
+	}
+
+	// View creation failures short-circuit without calling fn.
+	sn.viewErr = errdefs.ErrNotFound
+	err = WithView(ctx, sn, "missing", func([]mount.Mount) error {
+		t.Error("fn must not be called when the view cannot be created")
+		return nil
+	})
+	if !errdefs.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+// updateSnapshotter extends statSnapshotter with an Update recording the
+// committed info and fieldpaths.
+type updateSnapshotter struct {
+	statSnapshotter
+
+	updated    *Info
+	fieldpaths []string
+}
+
+func (u *updateSnapshotter) Update(ctx context.Context, info Info, fieldpaths ...string) (Info, error) {
+	u.updated = &info
+	u.fieldpaths = fieldpaths
+	return info, nil
+}
+
+func TestUpdateLabels(t *testing.T) {
+	ctx := context.Background()
+	sn := &updateSnapshotter{
+		statSnapshotter: statSnapshotter{
+			infos: map[string]Info{
+				"snap": {
+					Name: "snap",
+					Kind: KindCommitted,
+					Labels: map[string]string{
+						"keep":    "1",
+						"replace": "old",
+						"drop":    "x",
+					},
+				},
+			},
+		},
+	}
+
+	info, err := UpdateLabels(ctx, sn, "snap",
+		map[string]string{"replace": "new", "added": "2"},
+		[]string{"drop", "absent"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"keep":    "1",
+		"replace": "new",
+		"added":   "2",
+	}
+	if len(info.Labels) != len(expected) {
+		t.Fatalf("unexpected labels %v, expected %v", info.Labels, expected)
+	}
+	for k, v := range expected {
+		if info.Labels[k] != v {
+			t.Errorf("expected label %s=%s, got %q", k, v, info.Labels[k])
+		}
+	}
+	if sn.updated == nil {
+		t.Fatal("expected Update to be called")
+	}
+	if len(sn.fieldpaths) != 1 || sn.fieldpaths[0] != "labels" {
+		t.Errorf("expected labels fieldpath, got %v", sn.fieldpaths)
+	}
+	// The stored info's label map must not have been mutated in place.
+	if sn.infos["snap"].Labels["replace"] != "old" || sn.infos["snap"].Labels["drop"] != "x" {
+		t.Error("original label map was mutated")
+	}
+
+	if _, err := UpdateLabels(ctx, sn, "missing", nil, nil); !errdefs.IsNotFound(err) {
+		t.Errorf("expected not found for missing snapshot, got %v", err)
+	}
+}
+
+// usageSnapshotter extends walkSnapshotter with per-snapshot usage.
+type usageSnapshotter struct {
+	walkSnapshotter
+	usage map[string]Usage
+}
+
+func (u *usageSnapshotter) Usage(ctx context.Context, key string) (Usage, error) {
+	usage, ok := u.usage[key]
+	if !ok {
+		return Usage{}, errdefs.ErrNotFound
+	}
+	return usage, nil
+}
+
+func TestReclaimable(t *testing.T) {
+	ctx := context.Background()
+	// parent <- child-a, parent <- child-b
+	sn := &usageSnapshotter{
+		walkSnapshotter: walkSnapshotter{
+			infos: []Info{
+				{Name: "parent", Kind: KindCommitted},
+				{Name: "child-a", Parent: "parent", Kind: KindCommitted},
+				{Name: "child-b", Parent: "parent", Kind: KindCommitted},
+			},
+		},
+		usage: map[string]Usage{
+			"parent":  {Size: 100, Inodes: 10},
+			"child-a": {Size: 30, Inodes: 3},
+			"child-b": {Size: 50, Inodes: 5},
+		},
+	}
+
+	// Removing a leaf frees only its exclusive usage; the shared parent
+	// stays referenced by the other child.
+	u, err := Reclaimable(ctx, sn, []string{"child-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Size != 30 || u.Inodes != 3 {
+		t.Errorf("unexpected usage %+v, expected child-a only", u)
+	}
+
+	// Removing the shared parent alone frees nothing; both children keep
+	// it alive.
+	u, err = Reclaimable(ctx, sn, []string{"parent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Size != 0 || u.Inodes != 0 {
+		t.Errorf("unexpected usage %+v, expected nothing freed", u)
+	}
+
+	// Removing the whole subtree frees everything.
+	u, err = Reclaimable(ctx, sn, []string{"parent", "child-a", "child-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Size != 180 || u.Inodes != 18 {
+		t.Errorf("unexpected usage %+v, expected full forest", u)
+	}
+
+	if _, err := Reclaimable(ctx, sn, []string{"missing"}); !errdefs.IsNotFound(err) {
+		t.Errorf("expected not found for unknown key, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package streaming
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/typeurl/v2"
+)
+
+const defaultSubscriberBuffer = 32
+
+// BroadcasterOpt configures a Broadcaster.
+type BroadcasterOpt func(*Broadcaster)
+
+// WithSubscriberBuffer sets the per-subscriber message buffer size.
+func WithSubscriberBuffer(size int) BroadcasterOpt {
+	return func(b *Broadcaster) {
+		b.bufferSize = size
+	}
+}
+
+// WithBlockOnSlowSubscriber makes the broadcaster block the source stream
+// until a slow subscriber's buffer has room, instead of dropping messages.
+func WithBlockOnSlowSubscriber() BroadcasterOpt {
+	return func(b *Broadcaster) {
+		b.block = true
+	}
+}
+
+// Broadcaster fans out every message received from a single source stream to
+// any number of subscriber streams. Each subscriber receives the full message
+// sequence starting from its subscription, buffered independently. By default
+// messages are dropped for a subscriber whose buffer is full; see
+// WithBlockOnSlowSubscriber to block the source instead.
+type Broadcaster struct {
+	mu         sync.Mutex
+	subs       map[*subscriber]struct{}
+	bufferSize int
+	block      bool
+	err        error
+	done       chan struct{}
+}
+
+// NewBroadcaster returns a Broadcaster which consumes src until Recv fails.
+// When src is closed, all subscriber streams are closed with io.EOF after
+// their buffered messages are drained.
+func NewBroadcaster(src Stream, opts ...BroadcasterOpt) *Broadcaster {
+	b := &Broadcaster{
+		subs:       map[*subscriber]struct{}{},
+		bufferSize: defaultSubscriberBuffer,
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	go b.run(src)
+	return b
+}
+
+// Subscribe registers a new consumer stream receiving every message produced
+// by the source after this call. The returned cancel function unregisters the
+// subscriber and releases its buffer; it is safe to call multiple times.
+func (b *Broadcaster) Subscribe() (Stream, func()) {
+	s := &subscriber{
+		b:      b,
+		ch:     make(chan typeurl.Any, b.bufferSize),
+		closed: make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	select {
+	case <-b.done:
+		// Source already closed, subscriber only observes the stream error.
+		close(s.ch)
+	default:
+		b.subs[s] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return s, s.cancel
+}
+
+func (b *Broadcaster) run(src Stream) {
+	for {
+		msg, err := src.Recv()
+		if err != nil {
+			b.mu.Lock()
+			b.err = err
+			close(b.done)
+			for s := range b.subs {
+				close(s.ch)
+				delete(b.subs, s)
+			}
+			b.mu.Unlock()
+			return
+		}
+
+		b.mu.Lock()
+		subs := make([]*subscriber, 0, len(b.subs))
+		for s := range b.subs {
+			subs = append(subs, s)
+		}
+		b.mu.Unlock()
+
+		for _, s := range subs {
+			if b.block {
+				select {
+				case s.ch <- msg:
+				case <-s.closed:
+				}
+			} else {
+				select {
+				case s.ch <- msg:
+				default:
+					// Slow subscriber, drop the message.
+				}
+			}
+		}
+	}
+}
+
+func (b *Broadcaster) streamErr() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	return io.EOF
+}
+
+type subscriber struct {
+	b         *Broadcaster
+	ch        chan typeurl.Any
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *subscriber) Send(typeurl.Any) error {
+	return fmt.Errorf("broadcast subscribers are receive-only: %w", errdefs.ErrNotImplemented)
+}
+
+func (s *subscriber) Recv() (typeurl.Any, error) {
+	select {
+	case msg, ok := <-s.ch:
+		if !ok {
+			return nil, s.b.streamErr()
+		}
+		return msg, nil
+	case <-s.closed:
+		return nil, io.EOF
+	}
+}
+
+func (s *subscriber) Close() error {
+	s.cancel()
+	return nil
+}
+
+func (s *subscriber) cancel() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+	s.b.mu.Lock()
+	delete(s.b.subs, s)
+	s.b.mu.Unlock()
+}
@@ -0,0 +1,147 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package streaming
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/containerd/typeurl/v2"
+)
+
+type testAny struct {
+	url string
+}
+
+func (a *testAny) GetTypeUrl() string { return a.url }
+func (a *testAny) GetValue() []byte   { return nil }
+
+// chanStream is a Stream backed by a channel, used as a broadcast source.
+type chanStream struct {
+	ch chan typeurl.Any
+}
+
+func newChanStream() *chanStream {
+	return &chanStream{ch: make(chan typeurl.Any)}
+}
+
+func (s *chanStream) Send(a typeurl.Any) error {
+	s.ch <- a
+	return nil
+}
+
+func (s *chanStream) Recv() (typeurl.Any, error) {
+	a, ok := <-s.ch
+	if !ok {
+		return nil, io.EOF
+	}
+	return a, nil
+}
+
+func (s *chanStream) Close() error {
+	close(s.ch)
+	return nil
+}
+
+func recvTimeout(t *testing.T, s Stream) (typeurl.Any, error) {
+	t.Helper()
+	type result struct {
+		a   typeurl.Any
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		a, err := s.Recv()
+		ch <- result{a, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.a, r.err
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for Recv")
+		return nil, nil
+	}
+}
+
+func TestBroadcasterFanOut(t *testing.T) {
+	src := newChanStream()
+	b := NewBroadcaster(src)
+
+	s1, cancel1 := b.Subscribe()
+	defer cancel1()
+	s2, cancel2 := b.Subscribe()
+	defer cancel2()
+
+	var sent []string
+	for i := 0; i < 3; i++ {
+		url := fmt.Sprintf("test.io/message/%d", i)
+		sent = append(sent, url)
+		if err := src.Send(&testAny{url: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src.Close()
+
+	for _, s := range []Stream{s1, s2} {
+		for _, url := range sent {
+			a, err := recvTimeout(t, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.GetTypeUrl() != url {
+				t.Errorf("unexpected message %q, expected %q", a.GetTypeUrl(), url)
+			}
+		}
+		if _, err := recvTimeout(t, s); err != io.EOF {
+			t.Errorf("expected io.EOF after source close, got %v", err)
+		}
+	}
+}
+
+func TestBroadcasterSlowSubscriberDrops(t *testing.T) {
+	src := newChanStream()
+	b := NewBroadcaster(src, WithSubscriberBuffer(1))
+
+	s, cancel := b.Subscribe()
+	defer cancel()
+
+	// Fill the subscriber buffer, then overflow it; the extra messages
+	// must be dropped without blocking the source.
+	for i := 0; i < 5; i++ {
+		if err := src.Send(&testAny{url: fmt.Sprintf("test.io/message/%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src.Close()
+
+	received := 0
+	for {
+		_, err := recvTimeout(t, s)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		received++
+	}
+	if received == 0 || received >= 5 {
+		t.Errorf("expected some but not all messages for slow subscriber, got %d of 5", received)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package streaming
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/containerd/typeurl/v2"
+)
+
+// mockStream serves a fixed sequence of messages, ending with err.
+type mockStream struct {
+	msgs   []typeurl.Any
+	err    error
+	closed bool
+}
+
+func (s *mockStream) Send(typeurl.Any) error { return nil }
+
+func (s *mockStream) Recv() (typeurl.Any, error) {
+	if len(s.msgs) == 0 {
+		return nil, s.err
+	}
+	a := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return a, nil
+}
+
+func (s *mockStream) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestDrain(t *testing.T) {
+	s := &mockStream{
+		msgs: []typeurl.Any{&testAny{url: "a"}, &testAny{url: "b"}, &testAny{url: "c"}},
+		err:  io.EOF,
+	}
+	if err := Drain(s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.msgs) != 0 {
+		t.Errorf("%d messages left undrained", len(s.msgs))
+	}
+	if !s.closed {
+		t.Error("stream not closed")
+	}
+}
+
+func TestDrainRecvError(t *testing.T) {
+	errRecv := errors.New("connection reset")
+	s := &mockStream{
+		msgs: []typeurl.Any{&testAny{url: "a"}},
+		err:  errRecv,
+	}
+	if err := Drain(s); !errors.Is(err, errRecv) {
+		t.Fatalf("expected receive error, got %v", err)
+	}
+	if !s.closed {
+		t.Error("stream must be closed even on receive error")
+	}
+}
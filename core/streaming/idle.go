@@ -0,0 +1,128 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package streaming
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/containerd/typeurl/v2"
+)
+
+// NewIdleTimeoutStream wraps a stream so it is closed automatically when no
+// Send or Recv succeeds within the idle duration, releasing resources pinned
+// by clients which vanished without closing. Operations after the timeout
+// fires return a DeadlineExceeded error. An explicit Close stops the timer
+// cleanly. A non-positive idle duration returns the stream unchanged.
+func NewIdleTimeoutStream(s Stream, idle time.Duration) Stream {
+	if idle <= 0 {
+		return s
+	}
+	its := &idleTimeoutStream{
+		stream: s,
+		idle:   idle,
+	}
+	its.timer = time.AfterFunc(idle, its.timeout)
+	return its
+}
+
+type idleTimeoutStream struct {
+	stream Stream
+	idle   time.Duration
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	closed   bool
+	timedOut bool
+}
+
+func (s *idleTimeoutStream) timeout() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.timedOut = true
+	s.mu.Unlock()
+	// Closing the underlying stream unblocks any in-flight Recv, which then
+	// surfaces the timeout error.
+	s.stream.Close()
+}
+
+// active resets the idle timer after a successful operation.
+func (s *idleTimeoutStream) active() {
+	s.mu.Lock()
+	if !s.closed {
+		s.timer.Reset(s.idle)
+	}
+	s.mu.Unlock()
+}
+
+func (s *idleTimeoutStream) timeoutErr() error {
+	return fmt.Errorf("stream idle for %s: %w", s.idle, context.DeadlineExceeded)
+}
+
+func (s *idleTimeoutStream) isTimedOut() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.timedOut
+}
+
+func (s *idleTimeoutStream) Send(a typeurl.Any) error {
+	if s.isTimedOut() {
+		return s.timeoutErr()
+	}
+	err := s.stream.Send(a)
+	if err != nil {
+		if s.isTimedOut() {
+			return s.timeoutErr()
+		}
+		return err
+	}
+	s.active()
+	return nil
+}
+
+func (s *idleTimeoutStream) Recv() (typeurl.Any, error) {
+	if s.isTimedOut() {
+		return nil, s.timeoutErr()
+	}
+	msg, err := s.stream.Recv()
+	if err != nil {
+		if s.isTimedOut() {
+			return nil, s.timeoutErr()
+		}
+		return nil, err
+	}
+	s.active()
+	return msg, nil
+}
+
+func (s *idleTimeoutStream) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.timer.Stop()
+	s.mu.Unlock()
+	return s.stream.Close()
+}
@@ -0,0 +1,122 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package streaming
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/typeurl/v2"
+)
+
+// closableStream records whether Close was called; Send succeeds until then.
+type closableStream struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+func (s *closableStream) Send(typeurl.Any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errdefs.ErrUnavailable
+	}
+	return nil
+}
+
+func (s *closableStream) Recv() (typeurl.Any, error) {
+	return nil, errdefs.ErrUnavailable
+}
+
+func (s *closableStream) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *closableStream) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not reached within a second")
+}
+
+func TestIdleTimeoutStreamClosesInactive(t *testing.T) {
+	cs := &closableStream{}
+	s := NewIdleTimeoutStream(cs, 50*time.Millisecond)
+
+	waitFor(t, cs.isClosed)
+
+	if err := s.Send(nil); !errdefs.IsDeadlineExceeded(err) {
+		t.Errorf("expected deadline exceeded after idle timeout, got %v", err)
+	}
+	if _, err := s.Recv(); !errdefs.IsDeadlineExceeded(err) {
+		t.Errorf("expected deadline exceeded after idle timeout, got %v", err)
+	}
+}
+
+func TestIdleTimeoutStreamActivityResets(t *testing.T) {
+	cs := &closableStream{}
+	s := NewIdleTimeoutStream(cs, 100*time.Millisecond)
+
+	// Keep the stream busy for several multiples of the idle duration.
+	for i := 0; i < 10; i++ {
+		if err := s.Send(nil); err != nil {
+			t.Fatalf("unexpected send failure on iteration %d: %v", i, err)
+		}
+		time.Sleep(30 * time.Millisecond)
+	}
+	if cs.isClosed() {
+		t.Error("expected active stream to stay open")
+	}
+}
+
+func TestIdleTimeoutStreamExplicitClose(t *testing.T) {
+	cs := &closableStream{}
+	s := NewIdleTimeoutStream(cs, time.Minute)
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !cs.isClosed() {
+		t.Error("expected underlying stream to be closed")
+	}
+	// Closing again is a no-op rather than a double close.
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdleTimeoutStreamNonPositive(t *testing.T) {
+	cs := &closableStream{}
+	if s := NewIdleTimeoutStream(cs, 0); s != Stream(cs) {
+		t.Error("expected non-positive idle duration to return the stream unchanged")
+	}
+}
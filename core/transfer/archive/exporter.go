@@ -18,6 +18,7 @@ package archive
 
 import (
 	"context"
+	"errors"
 	"io"
 
 	"github.com/containerd/typeurl/v2"
@@ -61,6 +62,17 @@ func WithSkipNonDistributableBlobs(s *ImageExportStream) {
 	s.skipNonDistributable = true
 }
 
+// WithArchiveIndex appends a JSON offset table to the end of the archive
+// mapping each blob digest to its byte range in the tar, so a blob can be
+// extracted from a seekable file without scanning the whole archive. The
+// footer is only written when the output stream is seekable and is read
+// back with ReadArchiveIndex.
+func WithArchiveIndex() ExportOpt {
+	return func(s *ImageExportStream) {
+		s.archiveIndex = true
+	}
+}
+
 // NewImageExportStream returns an image exporter via tar stream
 func NewImageExportStream(stream io.WriteCloser, mediaType string, opts ...ExportOpt) *ImageExportStream {
 	s := &ImageExportStream{
@@ -73,6 +85,45 @@ func NewImageExportStream(stream io.WriteCloser, mediaType string, opts ...Expor
 	return s
 }
 
+// NewMultiImageExportStream returns an image exporter which fans the tar
+// stream out to every provided sink, e.g. a local file and an upload pipe,
+// without buffering the archive. All sinks are closed on completion and any
+// close errors are aggregated.
+func NewMultiImageExportStream(streams []io.WriteCloser, mediaType string, opts ...ExportOpt) *ImageExportStream {
+	writers := make([]io.Writer, len(streams))
+	for i, s := range streams {
+		writers[i] = s
+	}
+	s := &ImageExportStream{
+		stream: &multiWriteCloser{
+			Writer:  io.MultiWriter(writers...),
+			closers: streams,
+		},
+		mediaType: mediaType,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// multiWriteCloser fans writes out to all sinks and closes each of them,
+// joining any close errors.
+type multiWriteCloser struct {
+	io.Writer
+	closers []io.WriteCloser
+}
+
+func (m *multiWriteCloser) Close() error {
+	var errs []error
+	for _, c := range m.closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 type ImageExportStream struct {
 	stream    io.WriteCloser
 	mediaType string
@@ -81,6 +132,7 @@ type ImageExportStream struct {
 	allPlatforms              bool
 	skipCompatibilityManifest bool
 	skipNonDistributable      bool
+	archiveIndex              bool
 }
 
 func (iis *ImageExportStream) ExportStream(context.Context) (io.WriteCloser, string, error) {
@@ -106,6 +158,18 @@ func (iis *ImageExportStream) Export(ctx context.Context, cs content.Store, imgs
 	if iis.skipNonDistributable {
 		opts = append(opts, archive.WithSkipNonDistributableBlobs())
 	}
+	if iis.archiveIndex {
+		if _, ok := iis.stream.(io.Seeker); ok {
+			indexer := newArchiveIndexer(iis.stream)
+			if err := archive.Export(ctx, cs, indexer, opts...); err != nil {
+				return err
+			}
+			return indexer.writeFooter()
+		}
+		// A footer is only useful with random access, skip it for pipes
+		// and network streams.
+		log.G(ctx).Debug("archive index requested but stream is not seekable, skipping footer")
+	}
 	return archive.Export(ctx, cs, iis.stream, opts...)
 }
 
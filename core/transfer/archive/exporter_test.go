@@ -0,0 +1,216 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+)
+
+// closableBuffer records whether it was closed.
+type closableBuffer struct {
+	bytes.Buffer
+	closed   bool
+	closeErr error
+}
+
+func (b *closableBuffer) Close() error {
+	b.closed = true
+	return b.closeErr
+}
+
+func TestMultiImageExportStream(t *testing.T) {
+	ctx := context.Background()
+
+	var a, b closableBuffer
+	s := NewMultiImageExportStream([]io.WriteCloser{&a, &b}, ocispec.MediaTypeImageIndex)
+
+	stream, mediaType, err := s.ExportStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != ocispec.MediaTypeImageIndex {
+		t.Errorf("unexpected media type %q", mediaType)
+	}
+
+	payload := []byte("tar archive bytes")
+	if _, err := stream.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(a.Bytes(), payload) || !bytes.Equal(b.Bytes(), payload) {
+		t.Error("expected identical contents in both sinks")
+	}
+	if !a.closed || !b.closed {
+		t.Error("expected both sinks to be closed")
+	}
+}
+
+func TestMultiImageExportStreamCloseErrors(t *testing.T) {
+	errA := errors.New("sink a failed")
+	a := &closableBuffer{closeErr: errA}
+	b := &closableBuffer{}
+	s := NewMultiImageExportStream([]io.WriteCloser{a, b}, ocispec.MediaTypeImageIndex)
+
+	err := s.stream.Close()
+	if !errors.Is(err, errA) {
+		t.Errorf("expected aggregated close error, got %v", err)
+	}
+	if !b.closed {
+		t.Error("remaining sinks must still be closed after an error")
+	}
+}
+
+func writeExportTestImage(ctx context.Context, t *testing.T, cs content.Store) images.Image {
+	t.Helper()
+	writeBlob := func(mediaType string, b []byte) ocispec.Descriptor {
+		desc := ocispec.Descriptor{
+			MediaType: mediaType,
+			Digest:    digest.FromBytes(b),
+			Size:      int64(len(b)),
+		}
+		if err := content.WriteBlob(ctx, cs, "test-"+desc.Digest.String(), bytes.NewReader(b), desc); err != nil {
+			t.Fatal(err)
+		}
+		return desc
+	}
+
+	configJSON, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		RootFS:   ocispec.RootFS{Type: "layers", DiffIDs: []digest.Digest{digest.FromString("diff")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDesc := writeBlob(ocispec.MediaTypeImageConfig, configJSON)
+	layerDesc := writeBlob(ocispec.MediaTypeImageLayerGzip, []byte("layer tar bytes"))
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: ocispecs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDesc := writeBlob(ocispec.MediaTypeImageManifest, manifestJSON)
+	return images.Image{Name: "test:latest", Target: manifestDesc}
+}
+
+func TestExportWithArchiveIndex(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := writeExportTestImage(ctx, t, cs)
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "export.tar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	s := NewImageExportStream(f, ocispec.MediaTypeImageIndex, WithArchiveIndex(), WithAllPlatforms)
+	if err := s.Export(ctx, cs, []images.Image{img}); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := ReadArchiveIndex(f, fi.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every blob must be extractable by seeking to its recorded range.
+	if len(idx.Blobs) < 3 {
+		t.Fatalf("expected at least manifest, config, and layer entries, got %v", idx.Blobs)
+	}
+	for dgst, entry := range idx.Blobs {
+		b := make([]byte, entry.Size)
+		if _, err := f.ReadAt(b, entry.Offset); err != nil {
+			t.Fatalf("failed to read blob %s at %d: %v", dgst, entry.Offset, err)
+		}
+		if got := digest.FromBytes(b); got != dgst {
+			t.Errorf("blob at offset %d digests to %s, expected %s", entry.Offset, got, dgst)
+		}
+	}
+	if _, ok := idx.Blobs[img.Target.Digest]; !ok {
+		t.Errorf("expected manifest %s in index, got %v", img.Target.Digest, idx.Blobs)
+	}
+
+	// The archive itself must remain a valid tar.
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(f)
+	for {
+		if _, err := tr.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("archive no longer a valid tar: %v", err)
+		}
+	}
+}
+
+func TestExportWithArchiveIndexNotSeekable(t *testing.T) {
+	ctx := context.Background()
+	cs, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := writeExportTestImage(ctx, t, cs)
+
+	var buf closableBuffer
+	s := NewImageExportStream(&buf, ocispec.MediaTypeImageIndex, WithArchiveIndex(), WithAllPlatforms)
+	if err := s.Export(ctx, cs, []images.Image{img}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The footer is skipped for non-seekable streams and the plain tar is
+	// written unchanged.
+	b := buf.Bytes()
+	if _, err := ReadArchiveIndex(bytes.NewReader(b), int64(len(b))); !errdefs.IsNotFound(err) {
+		t.Errorf("expected not found for missing footer, got %v", err)
+	}
+	tr := tar.NewReader(bytes.NewReader(b))
+	if _, err := tr.Next(); err != nil {
+		t.Errorf("expected valid tar stream, got %v", err)
+	}
+}
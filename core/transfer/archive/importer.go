@@ -17,16 +17,23 @@
 package archive
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 
+	"github.com/containerd/platforms"
 	"github.com/containerd/typeurl/v2"
+	digest "github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
+	"github.com/containerd/containerd/v2/api/types"
 	transferapi "github.com/containerd/containerd/v2/api/types/transfer"
 	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
 	"github.com/containerd/containerd/v2/core/images/archive"
 	"github.com/containerd/containerd/v2/core/streaming"
+	"github.com/containerd/containerd/v2/core/transfer"
 	tstreaming "github.com/containerd/containerd/v2/core/transfer/streaming"
 	"github.com/containerd/containerd/v2/pkg/archive/compression"
 	"github.com/containerd/log"
@@ -38,6 +45,27 @@ func WithForceCompression(s *ImageImportStream) {
 	s.forceCompress = true
 }
 
+// WithImportPlatform limits the imported index to the provided platform,
+// symmetric to the exporter's WithPlatform. May be given multiple times to
+// import several platforms. Manifest entries without platform information
+// are always kept.
+func WithImportPlatform(p ocispec.Platform) ImportOpt {
+	return func(s *ImageImportStream) {
+		s.platforms = append(s.platforms, p)
+	}
+}
+
+// WithImportProgress reports the number of archive bytes consumed while
+// importing.
+//
+// The function cannot be serialized and is therefore local-only: it is
+// ignored by MarshalAny and never crosses the transfer service boundary.
+func WithImportProgress(f transfer.ProgressFunc) ImportOpt {
+	return func(s *ImageImportStream) {
+		s.progress = f
+	}
+}
+
 // NewImageImportStream returns a image importer via tar stream
 func NewImageImportStream(stream io.Reader, mediaType string, opts ...ImportOpt) *ImageImportStream {
 	s := &ImageImportStream{
@@ -54,6 +82,8 @@ type ImageImportStream struct {
 	stream        io.Reader
 	mediaType     string
 	forceCompress bool
+	platforms     []ocispec.Platform
+	progress      transfer.ProgressFunc
 }
 
 func (iis *ImageImportStream) ImportStream(context.Context) (io.Reader, string, error) {
@@ -67,8 +97,11 @@ func (iis *ImageImportStream) Import(ctx context.Context, store content.Store) (
 	}
 
 	r := iis.stream
+	if iis.progress != nil {
+		r = &progressReader{r: r, progress: iis.progress}
+	}
 	if iis.mediaType == "" {
-		d, err := compression.DecompressStream(iis.stream)
+		d, err := compression.DecompressStream(r)
 		if err != nil {
 			return ocispec.Descriptor{}, err
 		}
@@ -76,7 +109,80 @@ func (iis *ImageImportStream) Import(ctx context.Context, store content.Store) (
 		r = d
 	}
 
-	return archive.ImportIndex(ctx, store, r, opts...)
+	desc, err := archive.ImportIndex(ctx, store, r, opts...)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return iis.filterPlatforms(ctx, store, desc)
+}
+
+// progressReader reports the number of bytes consumed from the import
+// stream.
+type progressReader struct {
+	r        io.Reader
+	progress transfer.ProgressFunc
+	total    int64
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		pr.total += int64(n)
+		pr.progress(transfer.Progress{
+			Event:    "importing",
+			Name:     "archive",
+			Progress: pr.total,
+		})
+	}
+	return n, err
+}
+
+// filterPlatforms rewrites the imported index to only reference manifests
+// matching the configured platforms, keeping entries without platform
+// information. The full archive contents are still ingested; only the
+// returned index is narrowed.
+func (iis *ImageImportStream) filterPlatforms(ctx context.Context, store content.Store, desc ocispec.Descriptor) (ocispec.Descriptor, error) {
+	if len(iis.platforms) == 0 || !images.IsIndexType(desc.MediaType) {
+		return desc, nil
+	}
+
+	p, err := content.ReadBlob(ctx, store, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	var index ocispec.Index
+	if err := json.Unmarshal(p, &index); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	matcher := platforms.Any(iis.platforms...)
+	var (
+		filtered []ocispec.Descriptor
+		modified bool
+	)
+	for _, m := range index.Manifests {
+		if m.Platform == nil || matcher.Match(*m.Platform) {
+			filtered = append(filtered, m)
+		} else {
+			modified = true
+		}
+	}
+	if !modified {
+		return desc, nil
+	}
+
+	index.Manifests = filtered
+	b, err := json.Marshal(index)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	newDesc := desc
+	newDesc.Digest = digest.FromBytes(b)
+	newDesc.Size = int64(len(b))
+	if err := content.WriteBlob(ctx, store, "index-"+newDesc.Digest.String(), bytes.NewReader(b), newDesc); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return newDesc, nil
 }
 
 func (iis *ImageImportStream) MarshalAny(ctx context.Context, sm streaming.StreamCreator) (typeurl.Any, error) {
@@ -87,10 +193,20 @@ func (iis *ImageImportStream) MarshalAny(ctx context.Context, sm streaming.Strea
 	}
 	tstreaming.SendStream(ctx, iis.stream, stream)
 
+	// iis.progress is local-only and intentionally not serialized.
+	var specified []*types.Platform
+	for _, p := range iis.platforms {
+		specified = append(specified, &types.Platform{
+			OS:           p.OS,
+			Architecture: p.Architecture,
+			Variant:      p.Variant,
+		})
+	}
 	s := &transferapi.ImageImportStream{
 		Stream:        sid,
 		MediaType:     iis.mediaType,
 		ForceCompress: iis.forceCompress,
+		Platforms:     specified,
 	}
 
 	return typeurl.MarshalAny(s)
@@ -111,6 +227,7 @@ func (iis *ImageImportStream) UnmarshalAny(ctx context.Context, sm streaming.Str
 	iis.stream = tstreaming.ReceiveStream(ctx, stream)
 	iis.mediaType = s.MediaType
 	iis.forceCompress = s.ForceCompress
+	iis.platforms = types.OCIPlatformFromProto(s.Platforms)
 
 	return nil
 }
@@ -0,0 +1,103 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package archive
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/containerd/typeurl/v2"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/containerd/containerd/v2/core/streaming"
+	"github.com/containerd/containerd/v2/core/transfer"
+)
+
+// fakeStream is an in-memory streaming.Stream which discards sends and
+// reports EOF on receive, enough to drive MarshalAny/UnmarshalAny.
+type fakeStream struct{}
+
+func (fakeStream) Send(typeurl.Any) error     { return nil }
+func (fakeStream) Recv() (typeurl.Any, error) { return nil, io.EOF }
+func (fakeStream) Close() error               { return nil }
+
+type fakeStreamManager struct{}
+
+func (fakeStreamManager) Create(context.Context, string) (streaming.Stream, error) {
+	return fakeStream{}, nil
+}
+
+func (fakeStreamManager) Get(context.Context, string) (streaming.Stream, error) {
+	return fakeStream{}, nil
+}
+
+func TestImageImportStreamMarshalRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	sm := fakeStreamManager{}
+
+	src := NewImageImportStream(strings.NewReader("tar bytes"), "application/vnd.oci.image.layer.v1.tar",
+		WithForceCompression,
+		WithImportPlatform(ocispec.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}),
+		WithImportProgress(func(transfer.Progress) {}))
+
+	a, err := src.MarshalAny(ctx, sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dst ImageImportStream
+	if err := dst.UnmarshalAny(ctx, sm, a); err != nil {
+		t.Fatal(err)
+	}
+
+	if dst.mediaType != src.mediaType {
+		t.Errorf("expected media type %q, got %q", src.mediaType, dst.mediaType)
+	}
+	if !dst.forceCompress {
+		t.Error("expected force compress to survive the round trip")
+	}
+	if len(dst.platforms) != 1 {
+		t.Fatalf("expected 1 platform, got %d", len(dst.platforms))
+	}
+	if p := dst.platforms[0]; p.OS != "linux" || p.Architecture != "arm64" || p.Variant != "v8" {
+		t.Errorf("unexpected platform %+v", p)
+	}
+	if dst.stream == nil {
+		t.Error("expected stream to be wired up")
+	}
+}
+
+func TestImportProgressReader(t *testing.T) {
+	var last transfer.Progress
+	pr := &progressReader{
+		r: strings.NewReader("0123456789"),
+		progress: func(p transfer.Progress) {
+			last = p
+		},
+	}
+	if _, err := io.ReadAll(pr); err != nil {
+		t.Fatal(err)
+	}
+	if last.Progress != 10 {
+		t.Errorf("expected 10 bytes reported, got %d", last.Progress)
+	}
+	if last.Event != "importing" {
+		t.Errorf("unexpected event %q", last.Event)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package archive
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// archiveIndexMagic terminates an archive exported with WithArchiveIndex.
+// The footer is laid out as the JSON index, a fixed-width decimal length of
+// that JSON, and the magic, so a reader can locate the index from the end of
+// a seekable file without scanning the tar. Tar readers ignore bytes past
+// the end-of-archive blocks, so the footer does not break plain imports.
+const archiveIndexMagic = "containerd.archive.index.v1"
+
+// archiveIndexLenDigits is the width of the zero-padded decimal length field
+// preceding the magic.
+const archiveIndexLenDigits = 20
+
+// ArchiveIndex maps each blob in an exported archive to its byte range in
+// the tar, allowing a blob to be extracted from a seekable file without a
+// full scan.
+type ArchiveIndex struct {
+	Blobs map[digest.Digest]ArchiveIndexEntry `json:"blobs"`
+}
+
+// ArchiveIndexEntry is the byte range of one blob's data in the tar.
+type ArchiveIndexEntry struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+// ReadArchiveIndex reads the index footer from an archive of the given size
+// exported with WithArchiveIndex. It returns ErrNotFound when the archive
+// carries no footer.
+func ReadArchiveIndex(ra io.ReaderAt, size int64) (*ArchiveIndex, error) {
+	trailerLen := int64(len(archiveIndexMagic) + archiveIndexLenDigits)
+	if size < trailerLen {
+		return nil, fmt.Errorf("archive too small for index footer: %w", errdefs.ErrNotFound)
+	}
+	trailer := make([]byte, trailerLen)
+	if _, err := ra.ReadAt(trailer, size-trailerLen); err != nil {
+		return nil, err
+	}
+	if string(trailer[archiveIndexLenDigits:]) != archiveIndexMagic {
+		return nil, fmt.Errorf("no archive index footer: %w", errdefs.ErrNotFound)
+	}
+	jsonLen, err := strconv.ParseInt(string(trailer[:archiveIndexLenDigits]), 10, 64)
+	if err != nil || jsonLen < 0 || jsonLen > size-trailerLen {
+		return nil, fmt.Errorf("invalid archive index length %q", trailer[:archiveIndexLenDigits])
+	}
+	b := make([]byte, jsonLen)
+	if _, err := ra.ReadAt(b, size-trailerLen-jsonLen); err != nil {
+		return nil, err
+	}
+	var idx ArchiveIndex
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil, fmt.Errorf("invalid archive index: %w", err)
+	}
+	return &idx, nil
+}
+
+// archiveIndexer passes the tar stream through to the underlying writer
+// while parsing it on the side to record each blob's byte range. The parse
+// runs in a goroutine fed by a pipe so the export is never buffered.
+type archiveIndexer struct {
+	w    io.Writer
+	pw   *io.PipeWriter
+	done chan struct{}
+
+	entries  map[digest.Digest]ArchiveIndexEntry
+	parseErr error
+}
+
+func newArchiveIndexer(w io.Writer) *archiveIndexer {
+	pr, pw := io.Pipe()
+	a := &archiveIndexer{
+		w:       w,
+		pw:      pw,
+		done:    make(chan struct{}),
+		entries: map[digest.Digest]ArchiveIndexEntry{},
+	}
+	go func() {
+		defer close(a.done)
+		cr := &countingReader{r: pr}
+		tr := tar.NewReader(cr)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				if err != io.EOF {
+					a.parseErr = err
+				}
+				break
+			}
+			// After Next the reader is positioned at the start of the
+			// entry's data.
+			if dgst, ok := blobPathDigest(hdr.Name); ok {
+				a.entries[dgst] = ArchiveIndexEntry{Offset: cr.n, Size: hdr.Size}
+			}
+		}
+		// Drain the remainder so writes to the pipe never block.
+		io.Copy(io.Discard, pr) //nolint:errcheck
+	}()
+	return a
+}
+
+func (a *archiveIndexer) Write(p []byte) (int, error) {
+	n, err := a.w.Write(p)
+	if n > 0 {
+		a.pw.Write(p[:n]) //nolint:errcheck
+	}
+	return n, err
+}
+
+// writeFooter finishes the parse and appends the index footer to the
+// underlying writer.
+func (a *archiveIndexer) writeFooter() error {
+	a.pw.Close()
+	<-a.done
+	if a.parseErr != nil {
+		return fmt.Errorf("failed to index archive: %w", a.parseErr)
+	}
+	b, err := json.Marshal(ArchiveIndex{Blobs: a.entries})
+	if err != nil {
+		return err
+	}
+	trailer := fmt.Sprintf("%0*d%s", archiveIndexLenDigits, len(b), archiveIndexMagic)
+	if _, err := a.w.Write(append(b, trailer...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// blobPathDigest maps an OCI layout blob path such as "blobs/sha256/<hex>"
+// back to its digest.
+func blobPathDigest(name string) (digest.Digest, bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] != "blobs" {
+		return "", false
+	}
+	dgst := digest.NewDigestFromEncoded(digest.Algorithm(parts[1]), parts[2])
+	if dgst.Validate() != nil {
+		return "", false
+	}
+	return dgst, true
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
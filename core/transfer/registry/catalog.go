@@ -0,0 +1,124 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/containerd/containerd/v2/core/remotes/docker"
+	"github.com/containerd/containerd/v2/pkg/reference"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+)
+
+// Catalog enumerates the repositories of the registry via `GET /v2/_catalog`,
+// following `Link` header pagination until the registry reports no further
+// pages. limit bounds the page size requested from the registry; zero leaves
+// the registry's default. The request goes through the configured hosts,
+// transport, and credentials; registries without catalog support map to
+// ErrNotImplemented.
+func (r *OCIRegistry) Catalog(ctx context.Context, limit int) ([]string, error) {
+	if err := r.checkClosed(); err != nil {
+		return nil, err
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("catalog limit must not be negative: %w", errdefs.ErrInvalidArgument)
+	}
+	refspec, err := reference.Parse(r.reference)
+	if err != nil {
+		return nil, err
+	}
+	refhost := refspec.Hostname()
+	hosts, err := r.hosts(refhost)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	for _, host := range hosts {
+		if !host.Capabilities.Has(docker.HostCapabilityResolve) {
+			continue
+		}
+		repositories, err := listCatalog(ctx, host, limit)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			log.G(ctx).WithError(err).WithField("host", host.Host).Debug("failed to list catalog")
+			continue
+		}
+		return repositories, nil
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no host capable of listing the catalog for %s: %w", refhost, errdefs.ErrNotFound)
+	}
+	return nil, firstErr
+}
+
+// listCatalog pages through the catalog endpoint of a single host.
+func listCatalog(ctx context.Context, host docker.RegistryHost, limit int) ([]string, error) {
+	client := host.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := fmt.Sprintf("%s://%s%s/_catalog", host.Scheme, host.Host, host.Path)
+	if limit > 0 {
+		u = fmt.Sprintf("%s?n=%d", u, limit)
+	}
+
+	var repositories []string
+	for u != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range host.Header {
+			req.Header[k] = append(req.Header[k], v...)
+		}
+
+		resp, err := doWithAuth(ctx, client, host.Authorizer, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusNotFound {
+				return nil, fmt.Errorf("registry %s does not support the catalog API: %w", host.Host, errdefs.ErrNotImplemented)
+			}
+			return nil, fmt.Errorf("unexpected status %s listing catalog", resp.Status)
+		}
+
+		var page struct {
+			Repositories []string `json:"repositories"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid catalog response: %w", err)
+		}
+		repositories = append(repositories, page.Repositories...)
+
+		u, err = nextPageURL(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return repositories, nil
+}
@@ -0,0 +1,96 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/containerd/errdefs"
+)
+
+func TestCatalog(t *testing.T) {
+	ctx := context.Background()
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.String())
+		if r.URL.Path != "/v2/_catalog" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("last") == "" {
+			if n := r.URL.Query().Get("n"); n != "2" {
+				t.Errorf("unexpected page size %q, expected %q", n, "2")
+			}
+			w.Header().Set("Link", `</v2/_catalog?last=bar%2Fbaz&n=2>; rel="next"`)
+			fmt.Fprint(w, `{"repositories":["bar/app","bar/baz"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"repositories":["foo/bar"]}`)
+	}))
+	defer srv.Close()
+
+	host := srv.Listener.Addr().String()
+	r, err := NewOCIRegistry(ctx, host+"/foo/bar:latest", WithDefaultScheme("http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	repositories, err := r.Catalog(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"bar/app", "bar/baz", "foo/bar"}
+	if !reflect.DeepEqual(repositories, expected) {
+		t.Errorf("unexpected repositories %v, expected %v", repositories, expected)
+	}
+	if len(requests) != 2 {
+		t.Errorf("expected 2 paginated requests, got %v", requests)
+	}
+
+	if _, err := r.Catalog(ctx, -1); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("expected invalid argument for negative limit, got %v", err)
+	}
+}
+
+func TestCatalogNotSupported(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	host := srv.Listener.Addr().String()
+	r, err := NewOCIRegistry(ctx, host+"/foo/bar:latest", WithDefaultScheme("http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	_, err = r.Catalog(ctx, 0)
+	if !errdefs.IsNotImplemented(err) {
+		t.Errorf("expected catalog not supported error, got %v", err)
+	}
+}
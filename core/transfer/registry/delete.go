@@ -0,0 +1,113 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/core/remotes/docker"
+	"github.com/containerd/containerd/v2/pkg/reference"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Delete removes the given descriptor from the registry through the
+// configured transport and credentials, issuing
+// `DELETE /v2/<name>/manifests/<digest>` for manifests and indexes and
+// `DELETE /v2/<name>/blobs/<digest>` for anything else. A registry which
+// does not know the digest maps to ErrNotFound; one with deletion disabled
+// surfaces a clear error.
+func (r *OCIRegistry) Delete(ctx context.Context, desc ocispec.Descriptor) error {
+	if err := r.checkClosed(); err != nil {
+		return err
+	}
+	if err := desc.Digest.Validate(); err != nil {
+		return fmt.Errorf("invalid digest to delete: %w", errdefs.ErrInvalidArgument)
+	}
+	refspec, err := reference.Parse(r.reference)
+	if err != nil {
+		return err
+	}
+	refhost := refspec.Hostname()
+	repository := strings.TrimPrefix(refspec.Locator, refhost+"/")
+	hosts, err := r.hosts(refhost)
+	if err != nil {
+		return err
+	}
+
+	resource := "blobs"
+	if images.IsManifestType(desc.MediaType) || images.IsIndexType(desc.MediaType) {
+		resource = "manifests"
+	}
+
+	var firstErr error
+	for _, host := range hosts {
+		if !host.Capabilities.Has(docker.HostCapabilityPush) {
+			continue
+		}
+		err := deleteResource(ctx, host, repository, resource, desc)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			log.G(ctx).WithError(err).WithField("host", host.Host).Debug("failed to delete from registry")
+			continue
+		}
+		return nil
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no host capable of deleting from %s: %w", refhost, errdefs.ErrNotFound)
+	}
+	return firstErr
+}
+
+// deleteResource issues the delete against a single host.
+func deleteResource(ctx context.Context, host docker.RegistryHost, repository, resource string, desc ocispec.Descriptor) error {
+	client := host.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := fmt.Sprintf("%s://%s%s/%s/%s/%s", host.Scheme, host.Host, host.Path, repository, resource, desc.Digest.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range host.Header {
+		req.Header[k] = append(req.Header[k], v...)
+	}
+
+	resp, err := doWithAuth(ctx, client, host.Authorizer, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%s %s: %w", resource, desc.Digest, errdefs.ErrNotFound)
+	case http.StatusMethodNotAllowed:
+		return fmt.Errorf("registry %s has deletion disabled: %w", host.Host, errdefs.ErrFailedPrecondition)
+	default:
+		return fmt.Errorf("unexpected status %s deleting %s %s", resp.Status, resource, desc.Digest)
+	}
+}
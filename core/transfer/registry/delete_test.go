@@ -0,0 +1,103 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+
+	manifestDgst := digest.FromString("manifest")
+	blobDgst := digest.FromString("blob")
+	missingDgst := digest.FromString("missing")
+	disabledDgst := digest.FromString("disabled")
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method != http.MethodDelete {
+			http.Error(w, "unexpected method", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, missingDgst.String()):
+			http.NotFound(w, r)
+		case strings.HasSuffix(r.URL.Path, disabledDgst.String()):
+			http.Error(w, "delete disabled", http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer srv.Close()
+
+	host := srv.Listener.Addr().String()
+	r, err := NewOCIRegistry(ctx, host+"/foo/bar:latest", WithDefaultScheme("http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Manifests go through the manifests resource.
+	if err := r.Delete(ctx, ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    manifestDgst,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "DELETE /v2/foo/bar/manifests/" + manifestDgst.String(); requests[len(requests)-1] != want {
+		t.Errorf("unexpected request %q, expected %q", requests[len(requests)-1], want)
+	}
+
+	// Blobs go through the blobs resource.
+	if err := r.Delete(ctx, ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayerGzip,
+		Digest:    blobDgst,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "DELETE /v2/foo/bar/blobs/" + blobDgst.String(); requests[len(requests)-1] != want {
+		t.Errorf("unexpected request %q, expected %q", requests[len(requests)-1], want)
+	}
+
+	// 404 maps to not found.
+	err = r.Delete(ctx, ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    missingDgst,
+	})
+	if !errdefs.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	// 405 surfaces the deletion-disabled condition.
+	err = r.Delete(ctx, ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    disabledDgst,
+	})
+	if !errdefs.IsFailedPrecondition(err) || !strings.Contains(err.Error(), "deletion disabled") {
+		t.Errorf("expected deletion disabled error, got %v", err)
+	}
+}
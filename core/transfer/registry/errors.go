@@ -0,0 +1,101 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/containerd/containerd/v2/core/remotes/docker"
+	remoteerrors "github.com/containerd/containerd/v2/core/remotes/errors"
+	"github.com/containerd/errdefs"
+)
+
+// Typed classifications for resolver and fetcher failures, detectable with
+// errors.Is, so callers can prompt for credentials, fall back to a mirror, or
+// fail without string-matching the underlying error.
+var (
+	// ErrUnauthorized indicates the registry rejected the request for lack
+	// of (valid) credentials.
+	ErrUnauthorized = errors.New("registry authorization failed")
+
+	// ErrManifestNotFound indicates the requested reference does not exist
+	// on the registry.
+	ErrManifestNotFound = errors.New("manifest not found")
+
+	// ErrNetwork indicates the registry could not be reached at all, making
+	// a mirror or retry worthwhile.
+	ErrNetwork = errors.New("registry unreachable")
+)
+
+// classifiedError pairs the original error with its classification so both
+// remain visible to errors.Is and errors.As.
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// classifyError wraps resolver and fetcher errors with the matching typed
+// error, mapping from the HTTP status when one is available. Errors which fit
+// no category are returned unchanged.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if kind := classification(err); kind != nil {
+		return &classifiedError{kind: kind, err: err}
+	}
+	return err
+}
+
+func classification(err error) error {
+	var unexpected remoteerrors.ErrUnexpectedStatus
+	if errors.As(err, &unexpected) {
+		switch unexpected.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ErrUnauthorized
+		case http.StatusNotFound:
+			return ErrManifestNotFound
+		}
+		return nil
+	}
+	if errors.Is(err, docker.ErrInvalidAuthorization) {
+		return ErrUnauthorized
+	}
+	if errdefs.IsNotFound(err) {
+		return ErrManifestNotFound
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrNetwork
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return ErrNetwork
+	}
+	return nil
+}
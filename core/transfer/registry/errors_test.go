@@ -0,0 +1,105 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+
+	remoteerrors "github.com/containerd/containerd/v2/core/remotes/errors"
+	"github.com/containerd/errdefs"
+)
+
+func TestClassifyError(t *testing.T) {
+	statusErr := func(code int) error {
+		return remoteerrors.ErrUnexpectedStatus{
+			Status:     http.StatusText(code),
+			StatusCode: code,
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "Unauthorized",
+			err:      statusErr(http.StatusUnauthorized),
+			expected: ErrUnauthorized,
+		},
+		{
+			name:     "Forbidden",
+			err:      statusErr(http.StatusForbidden),
+			expected: ErrUnauthorized,
+		},
+		{
+			name:     "NotFoundStatus",
+			err:      statusErr(http.StatusNotFound),
+			expected: ErrManifestNotFound,
+		},
+		{
+			name:     "NotFoundErrdefs",
+			err:      fmt.Errorf("resolve: %w", errdefs.ErrNotFound),
+			expected: ErrManifestNotFound,
+		},
+		{
+			name: "Network",
+			err: &net.OpError{
+				Op:  "dial",
+				Err: os.NewSyscallError("connect", syscall.ECONNREFUSED),
+			},
+			expected: ErrNetwork,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			classified := classifyError(tc.err)
+			if !errors.Is(classified, tc.expected) {
+				t.Errorf("expected %v classification, got %v", tc.expected, classified)
+			}
+			// The original error stays visible through the wrapper.
+			if !errors.Is(classified, tc.err) && !errors.As(classified, new(*net.OpError)) {
+				var unexpected remoteerrors.ErrUnexpectedStatus
+				if !errors.As(classified, &unexpected) {
+					t.Errorf("expected original error to remain detectable, got %v", classified)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyErrorUnrecognized(t *testing.T) {
+	if err := classifyError(nil); err != nil {
+		t.Errorf("expected nil to stay nil, got %v", err)
+	}
+	plain := errors.New("something else")
+	if err := classifyError(plain); err != plain {
+		t.Errorf("expected unrecognized error to be returned unchanged, got %v", err)
+	}
+	if errors.Is(classifyError(statusTeapot()), ErrUnauthorized) {
+		t.Error("unexpected classification for unmapped status")
+	}
+}
+
+func statusTeapot() error {
+	return remoteerrors.ErrUnexpectedStatus{StatusCode: http.StatusTeapot}
+}
@@ -0,0 +1,108 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/containerd/containerd/v2/core/transfer"
+	"github.com/containerd/log"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/semaphore"
+)
+
+// limitedFetcher bounds the number of concurrent fetches against a registry.
+// A fetch counts as in flight until the returned reader is closed.
+type limitedFetcher struct {
+	fetcher transfer.Fetcher
+	sem     *semaphore.Weighted
+}
+
+func newLimitedFetcher(fetcher transfer.Fetcher, n int) *limitedFetcher {
+	return &limitedFetcher{
+		fetcher: fetcher,
+		sem:     semaphore.NewWeighted(int64(n)),
+	}
+}
+
+func (f *limitedFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	if err := f.sem.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	rc, err := f.fetcher.Fetch(ctx, desc)
+	if err != nil {
+		f.sem.Release(1)
+		return nil, err
+	}
+	return &releasingReadCloser{ReadCloser: rc, release: func() { f.sem.Release(1) }}, nil
+}
+
+// classifyingFetcher wraps fetch errors with their typed classification.
+type classifyingFetcher struct {
+	fetcher transfer.Fetcher
+}
+
+func (f classifyingFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	rc, err := f.fetcher.Fetch(ctx, desc)
+	return rc, classifyError(err)
+}
+
+// loggingFetcher emits a leveled event per blob fetch with a running count,
+// leaving the payload untouched.
+type loggingFetcher struct {
+	fetcher transfer.Fetcher
+	logger  *log.Entry
+	fetches atomic.Int64
+}
+
+func newLoggingFetcher(fetcher transfer.Fetcher, logger *log.Entry) *loggingFetcher {
+	return &loggingFetcher{
+		fetcher: fetcher,
+		logger:  logger,
+	}
+}
+
+func (f *loggingFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	logger := f.logger.WithFields(log.Fields{
+		"digest":    desc.Digest.String(),
+		"mediatype": desc.MediaType,
+		"size":      desc.Size,
+		"fetches":   f.fetches.Add(1),
+	})
+	logger.Debug("fetching blob")
+	rc, err := f.fetcher.Fetch(ctx, desc)
+	if err != nil {
+		logger.WithError(err).Debug("blob fetch failed")
+		return nil, err
+	}
+	return rc, nil
+}
+
+type releasingReadCloser struct {
+	io.ReadCloser
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (r *releasingReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.releaseOnce.Do(r.release)
+	return err
+}
@@ -0,0 +1,92 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// countingFetcher tracks the number of in-flight fetches, counting a fetch
+// until the returned reader is closed.
+type countingFetcher struct {
+	inflight atomic.Int32
+	max      atomic.Int32
+}
+
+func (f *countingFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	n := f.inflight.Add(1)
+	for {
+		max := f.max.Load()
+		if n <= max || f.max.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	// Hold the slot briefly so concurrent fetches overlap.
+	time.Sleep(time.Millisecond)
+	return &countingReadCloser{f: f, ReadCloser: io.NopCloser(strings.NewReader("blob"))}, nil
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+	f    *countingFetcher
+	once sync.Once
+}
+
+func (c *countingReadCloser) Close() error {
+	c.once.Do(func() { c.f.inflight.Add(-1) })
+	return c.ReadCloser.Close()
+}
+
+func TestLimitedFetcherBoundsConcurrency(t *testing.T) {
+	const (
+		limit   = 3
+		fetches = 20
+	)
+
+	cf := &countingFetcher{}
+	lf := newLimitedFetcher(cf, limit)
+
+	var wg sync.WaitGroup
+	for i := 0; i < fetches; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rc, err := lf.Fetch(context.Background(), ocispec.Descriptor{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := io.Copy(io.Discard, rc); err != nil {
+				t.Error(err)
+			}
+			rc.Close()
+		}()
+	}
+	wg.Wait()
+
+	if max := cf.max.Load(); max > limit {
+		t.Errorf("in-flight fetches reached %d, limit is %d", max, limit)
+	}
+}
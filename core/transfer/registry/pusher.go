@@ -0,0 +1,95 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/transfer"
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// pushState tracks blobs confirmed present on the registry, shared across
+// the pushers created for one OCIRegistry so a restarted push skips them
+// without re-probing.
+type pushState struct {
+	mu      sync.Mutex
+	present map[digest.Digest]struct{}
+}
+
+func newPushState() *pushState {
+	return &pushState{present: map[digest.Digest]struct{}{}}
+}
+
+func (s *pushState) isPresent(dgst digest.Digest) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.present[dgst]
+	return ok
+}
+
+func (s *pushState) markPresent(dgst digest.Digest) {
+	s.mu.Lock()
+	s.present[dgst] = struct{}{}
+	s.mu.Unlock()
+}
+
+// resumablePusher skips blobs known to exist on the registry. The underlying
+// docker pusher probes the registry with a HEAD request and reports existing
+// blobs as ErrAlreadyExists; this wrapper memoizes those results and records
+// successful commits, so restarting an interrupted push short-circuits the
+// blobs which already landed.
+type resumablePusher struct {
+	pusher transfer.Pusher
+	state  *pushState
+}
+
+func (p *resumablePusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	if p.state.isPresent(desc.Digest) {
+		return nil, fmt.Errorf("content %v on remote: %w", desc.Digest, errdefs.ErrAlreadyExists)
+	}
+	w, err := p.pusher.Push(ctx, desc)
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			p.state.markPresent(desc.Digest)
+		}
+		return nil, err
+	}
+	return &committingWriter{
+		Writer: w,
+		commit: func() { p.state.markPresent(desc.Digest) },
+	}, nil
+}
+
+// committingWriter invokes a callback once the wrapped writer commits.
+type committingWriter struct {
+	content.Writer
+	commit func()
+}
+
+func (w *committingWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	err := w.Writer.Commit(ctx, size, expected, opts...)
+	if err == nil || errdefs.IsAlreadyExists(err) {
+		w.commit()
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/streaming"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/typeurl/v2"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// fakeStream discards sends and reports EOF on receive, enough to drive
+// MarshalAny/UnmarshalAny.
+type fakeStream struct{}
+
+func (fakeStream) Send(typeurl.Any) error     { return nil }
+func (fakeStream) Recv() (typeurl.Any, error) { return nil, io.EOF }
+func (fakeStream) Close() error               { return nil }
+
+type fakeStreamManager struct{}
+
+func (fakeStreamManager) Create(context.Context, string) (streaming.Stream, error) {
+	return fakeStream{}, nil
+}
+
+func (fakeStreamManager) Get(context.Context, string) (streaming.Stream, error) {
+	return fakeStream{}, nil
+}
+
+// mockPusher reports the configured digests as already present, counting
+// probe calls.
+type mockPusher struct {
+	existing map[digest.Digest]struct{}
+	calls    int
+}
+
+func (p *mockPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	p.calls++
+	if _, ok := p.existing[desc.Digest]; ok {
+		return nil, fmt.Errorf("content %v on remote: %w", desc.Digest, errdefs.ErrAlreadyExists)
+	}
+	return nil, fmt.Errorf("unexpected push of %v: %w", desc.Digest, errdefs.ErrNotImplemented)
+}
+
+func TestResumablePusherSkipsExisting(t *testing.T) {
+	ctx := context.Background()
+	existing := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayerGzip,
+		Digest:    digest.FromString("already-pushed"),
+	}
+	mock := &mockPusher{existing: map[digest.Digest]struct{}{existing.Digest: {}}}
+	p := &resumablePusher{pusher: mock, state: newPushState()}
+
+	// First attempt probes the registry and learns the blob exists.
+	if _, err := p.Push(ctx, existing); !errdefs.IsAlreadyExists(err) {
+		t.Fatalf("expected already exists, got %v", err)
+	}
+	if mock.calls != 1 {
+		t.Fatalf("expected 1 probe, got %d", mock.calls)
+	}
+
+	// A retried push skips the registry round trip entirely.
+	if _, err := p.Push(ctx, existing); !errdefs.IsAlreadyExists(err) {
+		t.Fatalf("expected already exists, got %v", err)
+	}
+	if mock.calls != 1 {
+		t.Errorf("expected memoized result without a second probe, got %d calls", mock.calls)
+	}
+}
+
+func TestWithResumablePushMarshal(t *testing.T) {
+	ctx := context.Background()
+	reg, err := NewOCIRegistry(ctx, "registry.test/app:latest", WithResumablePush())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := reg.MarshalAny(ctx, fakeStreamManager{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst OCIRegistry
+	if err := dst.UnmarshalAny(ctx, fakeStreamManager{}, a); err != nil {
+		t.Fatal(err)
+	}
+	if dst.pushState == nil {
+		t.Error("expected resumable push to survive the round trip")
+	}
+}
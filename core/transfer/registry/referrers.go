@@ -0,0 +1,176 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/containerd/containerd/v2/core/remotes/docker"
+	"github.com/containerd/containerd/v2/pkg/reference"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Referrers lists the descriptors referring to the given subject digest via
+// the OCI referrers API (`GET /v2/<name>/referrers/<digest>`), falling back
+// to the referrers tag schema for registries without native support. The
+// result is filtered by artifactType when one is provided, client-side when
+// the registry did not apply the filter itself. Requests go through the
+// configured hosts, transport, and credentials.
+func (r *OCIRegistry) Referrers(ctx context.Context, subject digest.Digest, artifactType string) ([]ocispec.Descriptor, error) {
+	if err := r.checkClosed(); err != nil {
+		return nil, err
+	}
+	if err := subject.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid subject digest: %w", errdefs.ErrInvalidArgument)
+	}
+	refspec, err := reference.Parse(r.reference)
+	if err != nil {
+		return nil, err
+	}
+	refhost := refspec.Hostname()
+	repository := strings.TrimPrefix(refspec.Locator, refhost+"/")
+	hosts, err := r.hosts(refhost)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	for _, host := range hosts {
+		if !host.Capabilities.Has(docker.HostCapabilityResolve) {
+			continue
+		}
+		descs, err := listReferrers(ctx, host, repository, subject, artifactType)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			log.G(ctx).WithError(err).WithField("host", host.Host).Debug("failed to list referrers")
+			continue
+		}
+		return descs, nil
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no host capable of listing referrers for %s: %w", refhost, errdefs.ErrNotFound)
+	}
+	return nil, firstErr
+}
+
+// listReferrers queries the referrers endpoint of a single host, using the
+// tag-schema fallback when the endpoint is absent.
+func listReferrers(ctx context.Context, host docker.RegistryHost, repository string, subject digest.Digest, artifactType string) ([]ocispec.Descriptor, error) {
+	client := host.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := fmt.Sprintf("%s://%s%s/%s/referrers/%s", host.Scheme, host.Host, host.Path, repository, subject.String())
+	if artifactType != "" {
+		u += "?artifactType=" + url.QueryEscape(artifactType)
+	}
+
+	var descs []ocispec.Descriptor
+	for u != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", ocispec.MediaTypeImageIndex)
+		for k, v := range host.Header {
+			req.Header[k] = append(req.Header[k], v...)
+		}
+
+		resp, err := doWithAuth(ctx, client, host.Authorizer, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return referrersFromTagSchema(ctx, client, host, repository, subject, artifactType)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status %s listing referrers of %s", resp.Status, subject)
+		}
+		filtered := artifactType != "" && strings.Contains(resp.Header.Get("OCI-Filters-Applied"), "artifactType")
+
+		var index ocispec.Index
+		err = json.NewDecoder(resp.Body).Decode(&index)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid referrers response: %w", err)
+		}
+		for _, d := range index.Manifests {
+			if !filtered && artifactType != "" && d.ArtifactType != artifactType {
+				continue
+			}
+			descs = append(descs, d)
+		}
+
+		u, err = nextPageURL(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return descs, nil
+}
+
+// referrersFromTagSchema fetches the referrers index stored under the
+// fallback tag "<alg>-<encoded>". A missing tag means no referrers.
+func referrersFromTagSchema(ctx context.Context, client *http.Client, host docker.RegistryHost, repository string, subject digest.Digest, artifactType string) ([]ocispec.Descriptor, error) {
+	tag := subject.Algorithm().String() + "-" + subject.Encoded()
+	u := fmt.Sprintf("%s://%s%s/%s/manifests/%s", host.Scheme, host.Host, host.Path, repository, tag)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ocispec.MediaTypeImageIndex)
+	for k, v := range host.Header {
+		req.Header[k] = append(req.Header[k], v...)
+	}
+
+	resp, err := doWithAuth(ctx, client, host.Authorizer, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s fetching referrers tag %s", resp.Status, tag)
+	}
+
+	var index ocispec.Index
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("invalid referrers tag manifest: %w", err)
+	}
+	var descs []ocispec.Descriptor
+	for _, d := range index.Manifests {
+		if artifactType != "" && d.ArtifactType != artifactType {
+			continue
+		}
+		descs = append(descs, d)
+	}
+	return descs, nil
+}
@@ -0,0 +1,136 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func referrersIndex(t *testing.T, w http.ResponseWriter, manifests ...ocispec.Descriptor) {
+	t.Helper()
+	w.Header().Set("Content-Type", ocispec.MediaTypeImageIndex)
+	if err := json.NewEncoder(w).Encode(ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: manifests,
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReferrers(t *testing.T) {
+	ctx := context.Background()
+	subject := digest.FromString("subject manifest")
+	sbom := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/spdx+json",
+		Digest:       digest.FromString("sbom"),
+		Size:         10,
+	}
+	sig := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/vnd.dev.cosign.simplesigning.v1+json",
+		Digest:       digest.FromString("signature"),
+		Size:         11,
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/foo/bar/referrers/"+subject.String() {
+			http.NotFound(w, r)
+			return
+		}
+		// The filter is not applied server-side, so no
+		// OCI-Filters-Applied header is set.
+		referrersIndex(t, w, sbom, sig)
+	}))
+	defer srv.Close()
+
+	host := srv.Listener.Addr().String()
+	r, err := NewOCIRegistry(ctx, host+"/foo/bar:latest", WithDefaultScheme("http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	descs, err := r.Referrers(ctx, subject, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descs) != 2 {
+		t.Fatalf("expected both referrers, got %v", descs)
+	}
+
+	descs, err = r.Referrers(ctx, subject, sbom.ArtifactType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descs) != 1 || descs[0].Digest != sbom.Digest {
+		t.Errorf("expected only the SBOM referrer, got %v", descs)
+	}
+}
+
+func TestReferrersTagSchemaFallback(t *testing.T) {
+	ctx := context.Background()
+	subject := digest.FromString("subject manifest")
+	sbom := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/spdx+json",
+		Digest:       digest.FromString("sbom"),
+		Size:         10,
+	}
+	tag := subject.Algorithm().String() + "-" + subject.Encoded()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/foo/bar/manifests/"+tag {
+			// No native referrers support.
+			http.NotFound(w, r)
+			return
+		}
+		referrersIndex(t, w, sbom)
+	}))
+	defer srv.Close()
+
+	host := srv.Listener.Addr().String()
+	r, err := NewOCIRegistry(ctx, host+"/foo/bar:latest", WithDefaultScheme("http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	descs, err := r.Referrers(ctx, subject, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descs) != 1 || descs[0].Digest != sbom.Digest {
+		t.Errorf("expected the fallback referrer, got %v", descs)
+	}
+
+	// A missing fallback tag means no referrers, not an error.
+	descs, err = r.Referrers(ctx, digest.FromString("other"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descs) != 0 {
+		t.Errorf("expected no referrers, got %v", descs)
+	}
+}
@@ -18,12 +18,15 @@ package registry
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	transfertypes "github.com/containerd/containerd/v2/api/types/transfer"
 	"github.com/containerd/containerd/v2/core/remotes"
@@ -33,9 +36,12 @@ import (
 	"github.com/containerd/containerd/v2/core/transfer"
 	"github.com/containerd/containerd/v2/core/transfer/plugins"
 	tstreaming "github.com/containerd/containerd/v2/core/transfer/streaming"
+	"github.com/containerd/errdefs"
 	"github.com/containerd/log"
 	"github.com/containerd/typeurl/v2"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func init() {
@@ -44,10 +50,28 @@ func init() {
 }
 
 type registryOpts struct {
-	headers       http.Header
-	creds         CredentialHelper
-	hostDir       string
-	defaultScheme string
+	headers            http.Header
+	headerFunc         func(*http.Request)
+	creds              CredentialHelper
+	hostDir            string
+	defaultScheme      string
+	blobConcurrency    int
+	proactiveRefresh   bool
+	acceptMediaTypes   []string
+	transportTuning    transportTuning
+	logger             *log.Entry
+	resumablePush      bool
+	insecureSkipVerify bool
+}
+
+// transportTuning holds idle connection settings applied to the HTTP
+// transports created for registry hosts. Zero values keep the transport
+// defaults.
+type transportTuning struct {
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	connectTimeout      time.Duration
 }
 
 // Opt sets registry-related configurations.
@@ -61,6 +85,101 @@ func WithHeaders(headers http.Header) Opt {
 	}
 }
 
+// WithHeaderFunc configures a callback invoked before each outgoing request
+// to mutate its headers, for dynamic values such as trace or correlation IDs
+// which cannot be expressed as static headers.
+//
+// The function cannot be serialized and is therefore local-only: it is
+// ignored by MarshalAny and never crosses the transfer service boundary.
+func WithHeaderFunc(f func(req *http.Request)) Opt {
+	return func(o *registryOpts) error {
+		o.headerFunc = f
+		return nil
+	}
+}
+
+// WithAcceptMediaTypes restricts the Accept header sent on manifest requests
+// to exactly the provided media types, for registries which misbehave when
+// offered the full default set. An empty list keeps the default broad
+// behavior of accepting all supported manifest and index types.
+func WithAcceptMediaTypes(types ...string) Opt {
+	return func(o *registryOpts) error {
+		o.acceptMediaTypes = types
+		return nil
+	}
+}
+
+// WithTransportTuning configures the idle connection settings of the HTTP
+// transports created for registry hosts, for high-throughput mirrors where
+// the defaults cause connection churn. Zero values mean the transport
+// defaults are used.
+func WithTransportTuning(maxIdleConns, maxIdleConnsPerHost int, idleTimeout time.Duration) Opt {
+	return func(o *registryOpts) error {
+		if maxIdleConns < 0 || maxIdleConnsPerHost < 0 || idleTimeout < 0 {
+			return fmt.Errorf("transport tuning values must not be negative")
+		}
+		o.transportTuning = transportTuning{
+			maxIdleConns:        maxIdleConns,
+			maxIdleConnsPerHost: maxIdleConnsPerHost,
+			idleConnTimeout:     idleTimeout,
+		}
+		return nil
+	}
+}
+
+// WithConnectTimeout bounds how long establishing a connection to a registry
+// host may take, covering both the TCP dial and the TLS handshake. It is
+// independent of any overall request timeout: a slow transfer over an
+// established connection is unaffected. Zero keeps the transport defaults.
+func WithConnectTimeout(d time.Duration) Opt {
+	return func(o *registryOpts) error {
+		if d < 0 {
+			return fmt.Errorf("connect timeout must not be negative")
+		}
+		o.transportTuning.connectTimeout = d
+		return nil
+	}
+}
+
+// WithLogger configures a logger used to emit structured, leveled events
+// for resolver and fetch activity (resolve start/end, per-blob fetches with a
+// running count) without dumping request or payload bytes the way
+// WithHTTPDebug-style tracing does.
+//
+// The logger cannot be serialized and is therefore local-only: it is ignored
+// by MarshalAny and never crosses the transfer service boundary.
+func WithLogger(logger *log.Entry) Opt {
+	return func(o *registryOpts) error {
+		if logger == nil {
+			return fmt.Errorf("logger must not be nil")
+		}
+		o.logger = logger
+		return nil
+	}
+}
+
+// WithResumablePush resumes interrupted pushes by skipping blobs already
+// present on the registry: existence probes and successful commits are
+// remembered for the lifetime of the registry, so a restarted push does not
+// re-upload blobs which already landed.
+func WithResumablePush() Opt {
+	return func(o *registryOpts) error {
+		o.resumablePush = true
+		return nil
+	}
+}
+
+// WithInsecureSkipTLSVerify disables TLS certificate verification on the
+// registry hosts, for development registries with self-signed certificates.
+// It must never be used against production registries; a warning is emitted
+// when the registry is created with it.
+func WithInsecureSkipTLSVerify() Opt {
+	return func(o *registryOpts) error {
+		o.insecureSkipVerify = true
+		return nil
+	}
+}
+
 // WithCredentials configures a helper that provides credentials for a host.
 func WithCredentials(creds CredentialHelper) Opt {
 	return func(o *registryOpts) error {
@@ -85,6 +204,30 @@ func WithDefaultScheme(s string) Opt {
 	}
 }
 
+// WithProactiveTokenRefresh re-requests credentials from the credential
+// helper shortly before a previously returned token expires, instead of
+// waiting for a 401 to fail an in-flight upload chunk. Helpers opt in by
+// setting ExpiresAt on the returned Credentials; credentials without an
+// expiry are never cached and behave as before.
+func WithProactiveTokenRefresh() Opt {
+	return func(o *registryOpts) error {
+		o.proactiveRefresh = true
+		return nil
+	}
+}
+
+// WithBlobConcurrency bounds the number of blob fetches which may run
+// concurrently against the registry. Zero means unbounded.
+func WithBlobConcurrency(n int) Opt {
+	return func(o *registryOpts) error {
+		if n < 0 {
+			return fmt.Errorf("blob concurrency must not be negative: %d", n)
+		}
+		o.blobConcurrency = n
+		return nil
+	}
+}
+
 // NewOCIRegistry initializes with hosts, authorizer callback, and headers
 func NewOCIRegistry(ctx context.Context, ref string, opts ...Opt) (*OCIRegistry, error) {
 	var ropts registryOpts
@@ -97,6 +240,9 @@ func NewOCIRegistry(ctx context.Context, ref string, opts ...Opt) (*OCIRegistry,
 	if ropts.hostDir != "" {
 		hostOptions.HostDir = config.HostDirFromRoot(ropts.hostDir)
 	}
+	if ropts.proactiveRefresh && ropts.creds != nil {
+		ropts.creds = newRefreshingCredHelper(ropts.creds)
+	}
 	if ropts.creds != nil {
 		// TODO: Support bearer
 		hostOptions.Credentials = func(host string) (string, string, error) {
@@ -111,18 +257,51 @@ func NewOCIRegistry(ctx context.Context, ref string, opts ...Opt) (*OCIRegistry,
 	if ropts.defaultScheme != "" {
 		hostOptions.DefaultScheme = ropts.defaultScheme
 	}
-	resolver := docker.NewResolver(docker.ResolverOptions{
-		Hosts:   config.ConfigureHosts(ctx, hostOptions),
-		Headers: ropts.headers,
+	r := &OCIRegistry{
+		reference:        ref,
+		headers:          ropts.headers,
+		headerFunc:       ropts.headerFunc,
+		creds:            ropts.creds,
+		hostDir:          ropts.hostDir,
+		defaultScheme:    ropts.defaultScheme,
+		blobConcurrency:  ropts.blobConcurrency,
+		acceptMediaTypes: ropts.acceptMediaTypes,
+		transportTuning:  ropts.transportTuning,
+		logger:           ropts.logger,
+	}
+	if ropts.resumablePush {
+		r.pushState = newPushState()
+	}
+	if ropts.insecureSkipVerify {
+		r.insecureSkipVerify = true
+		l := log.G(ctx)
+		if r.logger != nil {
+			l = r.logger
+		}
+		l.WithField("ref", ref).Warn("TLS certificate verification disabled for registry")
+	}
+	hostOptions.UpdateClient = r.trackClient
+	r.hosts = config.ConfigureHosts(ctx, hostOptions)
+	r.resolver = docker.NewResolver(docker.ResolverOptions{
+		Hosts:   r.hosts,
+		Headers: resolverHeaders(ropts.headers, ropts.acceptMediaTypes),
 	})
-	return &OCIRegistry{
-		reference:     ref,
-		headers:       ropts.headers,
-		creds:         ropts.creds,
-		resolver:      resolver,
-		hostDir:       ropts.hostDir,
-		defaultScheme: ropts.defaultScheme,
-	}, nil
+	return r, nil
+}
+
+// resolverHeaders returns the headers to pass to the resolver, overriding the
+// Accept header with the configured media types when set. The caller's header
+// map is never mutated.
+func resolverHeaders(headers http.Header, acceptMediaTypes []string) http.Header {
+	if len(acceptMediaTypes) == 0 {
+		return headers
+	}
+	h := http.Header{}
+	for k, v := range headers {
+		h[k] = v
+	}
+	h.Set("Accept", strings.Join(acceptMediaTypes, ", "))
+	return h
 }
 
 // From stream
@@ -135,25 +314,136 @@ type Credentials struct {
 	Username string
 	Secret   string
 	Header   string
+
+	// ExpiresAt is the time the credentials stop being valid, when known.
+	// A zero value means the expiry is unknown.
+	ExpiresAt time.Time
 }
 
 // OCI
 type OCIRegistry struct {
 	reference string
 
-	headers http.Header
-	creds   CredentialHelper
+	headers    http.Header
+	headerFunc func(*http.Request)
+	creds      CredentialHelper
 
 	resolver remotes.Resolver
+	hosts    docker.RegistryHosts
 
 	hostDir string
 
 	defaultScheme string
 
+	blobConcurrency int
+
+	acceptMediaTypes []string
+
+	transportTuning transportTuning
+
+	logger *log.Entry
+
+	pushState *pushState
+
+	insecureSkipVerify bool
+
+	mu         sync.Mutex
+	transports []transportCloser
+	closed     bool
+
 	// This could be an interface which returns resolver?
 	// Resolver could also be a plug-able interface, to call out to a program to fetch?
 }
 
+// transportCloser is the subset of http.Transport needed to release idle
+// connections.
+type transportCloser interface {
+	CloseIdleConnections()
+}
+
+// trackClient records the transport of an HTTP client created for a registry
+// host so Close can later release its idle connections, and installs the
+// header callback when one is configured.
+func (r *OCIRegistry) trackClient(c *http.Client) error {
+	if t, ok := c.Transport.(*http.Transport); ok {
+		if r.insecureSkipVerify {
+			if t.TLSClientConfig == nil {
+				t.TLSClientConfig = &tls.Config{}
+			}
+			t.TLSClientConfig.InsecureSkipVerify = true
+		}
+		if r.transportTuning.maxIdleConns > 0 {
+			t.MaxIdleConns = r.transportTuning.maxIdleConns
+		}
+		if r.transportTuning.maxIdleConnsPerHost > 0 {
+			t.MaxIdleConnsPerHost = r.transportTuning.maxIdleConnsPerHost
+		}
+		if r.transportTuning.idleConnTimeout > 0 {
+			t.IdleConnTimeout = r.transportTuning.idleConnTimeout
+		}
+		if r.transportTuning.connectTimeout > 0 {
+			t.DialContext = (&net.Dialer{
+				Timeout: r.transportTuning.connectTimeout,
+			}).DialContext
+			t.TLSHandshakeTimeout = r.transportTuning.connectTimeout
+		}
+	}
+	if t, ok := c.Transport.(transportCloser); ok {
+		r.mu.Lock()
+		r.transports = append(r.transports, t)
+		r.mu.Unlock()
+	}
+	if r.headerFunc != nil {
+		c.Transport = &headerFuncTransport{base: c.Transport, fn: r.headerFunc}
+	}
+	return nil
+}
+
+// headerFuncTransport invokes a callback to mutate request headers before
+// delegating to the underlying round tripper.
+type headerFuncTransport struct {
+	base http.RoundTripper
+	fn   func(*http.Request)
+}
+
+func (t *headerFuncTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Clone per the RoundTripper contract: the original request must not
+	// be mutated.
+	req = req.Clone(req.Context())
+	t.fn(req)
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// Close releases idle connections held by HTTP transports created for this
+// registry and invalidates the resolver. The registry must not be used after
+// Close returns.
+func (r *OCIRegistry) Close() error {
+	r.mu.Lock()
+	transports := r.transports
+	r.transports = nil
+	r.closed = true
+	r.resolver = nil
+	r.mu.Unlock()
+	for _, t := range transports {
+		t.CloseIdleConnections()
+	}
+	return nil
+}
+
+// checkClosed returns an error when the registry has been closed.
+func (r *OCIRegistry) checkClosed() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("registry %s is closed: %w", r.reference, errdefs.ErrUnavailable)
+	}
+	return nil
+}
+
 func (r *OCIRegistry) String() string {
 	return fmt.Sprintf("OCI Registry (%s)", r.reference)
 }
@@ -163,24 +453,69 @@ func (r *OCIRegistry) Image() string {
 }
 
 func (r *OCIRegistry) Resolve(ctx context.Context) (name string, desc ocispec.Descriptor, err error) {
-	return r.resolver.Resolve(ctx, r.reference)
+	if err := r.checkClosed(); err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+	if r.logger != nil {
+		r.logger.WithField("ref", r.reference).Debug("resolving")
+	}
+	name, desc, err = r.resolver.Resolve(ctx, r.reference)
+	err = classifyError(err)
+	if r.logger != nil {
+		if err != nil {
+			r.logger.WithError(err).WithField("ref", r.reference).Debug("resolve failed")
+		} else {
+			r.logger.WithFields(log.Fields{
+				"ref":       name,
+				"digest":    desc.Digest.String(),
+				"mediatype": desc.MediaType,
+			}).Debug("resolved")
+		}
+	}
+	return name, desc, err
 }
 
 func (r *OCIRegistry) Fetcher(ctx context.Context, ref string) (transfer.Fetcher, error) {
-	return r.resolver.Fetcher(ctx, ref)
+	if err := r.checkClosed(); err != nil {
+		return nil, err
+	}
+	f, err := r.resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	f = classifyingFetcher{fetcher: f}
+	if r.blobConcurrency > 0 {
+		f = newLimitedFetcher(f, r.blobConcurrency)
+	}
+	if r.logger != nil {
+		f = newLoggingFetcher(f, r.logger)
+	}
+	return f, nil
 }
 
 func (r *OCIRegistry) Pusher(ctx context.Context, desc ocispec.Descriptor) (transfer.Pusher, error) {
+	if err := r.checkClosed(); err != nil {
+		return nil, err
+	}
 	var ref = r.reference
 	// Annotate ref with digest to push only push tag for single digest
 	if !strings.Contains(ref, "@") {
 		ref = ref + "@" + desc.Digest.String()
 	}
-	return r.resolver.Pusher(ctx, ref)
+	p, err := r.resolver.Pusher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if r.pushState != nil {
+		return &resumablePusher{pusher: p, state: r.pushState}, nil
+	}
+	return p, nil
 }
 
 func (r *OCIRegistry) MarshalAny(ctx context.Context, sm streaming.StreamCreator) (typeurl.Any, error) {
 	res := &transfertypes.RegistryResolver{}
+	// r.headerFunc and r.logger are local-only and intentionally not
+	// serialized.
 	if r.headers != nil {
 		res.Headers = map[string]string{}
 		for k := range r.headers {
@@ -230,6 +565,9 @@ func (r *OCIRegistry) MarshalAny(ctx context.Context, sm streaming.StreamCreator
 					resp.AuthType = transfertypes.AuthType_REFRESH
 					resp.Secret = creds.Secret
 				}
+				if !creds.ExpiresAt.IsZero() {
+					resp.ExpireAt = timestamppb.New(creds.ExpiresAt)
+				}
 
 				a, err := typeurl.MarshalAny(&resp)
 				if err != nil {
@@ -250,6 +588,18 @@ func (r *OCIRegistry) MarshalAny(ctx context.Context, sm streaming.StreamCreator
 	}
 	res.HostDir = r.hostDir
 	res.DefaultScheme = r.defaultScheme
+	res.BlobConcurrency = uint32(r.blobConcurrency)
+	res.AcceptMediaTypes = r.acceptMediaTypes
+	res.MaxIdleConns = uint32(r.transportTuning.maxIdleConns)
+	res.MaxIdleConnsPerHost = uint32(r.transportTuning.maxIdleConnsPerHost)
+	if r.transportTuning.idleConnTimeout > 0 {
+		res.IdleConnTimeout = durationpb.New(r.transportTuning.idleConnTimeout)
+	}
+	if r.transportTuning.connectTimeout > 0 {
+		res.ConnectTimeout = durationpb.New(r.transportTuning.connectTimeout)
+	}
+	res.ResumablePush = r.pushState != nil
+	res.InsecureSkipTlsVerify = r.insecureSkipVerify
 	s := &transfertypes.OCIRegistry{
 		Reference: r.reference,
 		Resolver:  res,
@@ -272,6 +622,25 @@ func (r *OCIRegistry) UnmarshalAny(ctx context.Context, sm streaming.StreamGette
 		if s.Resolver.DefaultScheme != "" {
 			hostOptions.DefaultScheme = s.Resolver.DefaultScheme
 		}
+		r.blobConcurrency = int(s.Resolver.BlobConcurrency)
+		r.acceptMediaTypes = s.Resolver.AcceptMediaTypes
+		r.transportTuning = transportTuning{
+			maxIdleConns:        int(s.Resolver.MaxIdleConns),
+			maxIdleConnsPerHost: int(s.Resolver.MaxIdleConnsPerHost),
+		}
+		if s.Resolver.IdleConnTimeout != nil {
+			r.transportTuning.idleConnTimeout = s.Resolver.IdleConnTimeout.AsDuration()
+		}
+		if s.Resolver.ConnectTimeout != nil {
+			r.transportTuning.connectTimeout = s.Resolver.ConnectTimeout.AsDuration()
+		}
+		if s.Resolver.ResumablePush {
+			r.pushState = newPushState()
+		}
+		if s.Resolver.InsecureSkipTlsVerify {
+			r.insecureSkipVerify = true
+			log.G(ctx).WithField("ref", s.Reference).Warn("TLS certificate verification disabled for registry")
+		}
 		if sid := s.Resolver.AuthStream; sid != "" {
 			stream, err := sm.Get(ctx, sid)
 			if err != nil {
@@ -297,9 +666,11 @@ func (r *OCIRegistry) UnmarshalAny(ctx context.Context, sm streaming.StreamGette
 	}
 
 	r.reference = s.Reference
+	hostOptions.UpdateClient = r.trackClient
+	r.hosts = config.ConfigureHosts(ctx, hostOptions)
 	r.resolver = docker.NewResolver(docker.ResolverOptions{
-		Hosts:   config.ConfigureHosts(ctx, hostOptions),
-		Headers: r.headers,
+		Hosts:   r.hosts,
+		Headers: resolverHeaders(r.headers, r.acceptMediaTypes),
 	})
 
 	return nil
@@ -345,6 +716,53 @@ func (cc *credCallback) GetCredentials(ctx context.Context, ref, host string) (C
 	case transfertypes.AuthType_HEADER:
 		creds.Header = s.Secret
 	}
+	if s.ExpireAt != nil {
+		creds.ExpiresAt = s.ExpireAt.AsTime()
+	}
 
 	return creds, nil
 }
+
+// refreshAhead is how long before a token's expiry it is proactively
+// refreshed.
+const refreshAhead = 30 * time.Second
+
+// refreshingCredHelper caches credentials per host and re-requests them from
+// the wrapped helper before they expire, so long-running transfers do not
+// hit a 401 mid-stream.
+type refreshingCredHelper struct {
+	helper CredentialHelper
+
+	mu     sync.Mutex
+	cached map[string]Credentials
+}
+
+func newRefreshingCredHelper(helper CredentialHelper) *refreshingCredHelper {
+	return &refreshingCredHelper{
+		helper: helper,
+		cached: make(map[string]Credentials),
+	}
+}
+
+func (h *refreshingCredHelper) GetCredentials(ctx context.Context, ref, host string) (Credentials, error) {
+	h.mu.Lock()
+	if c, ok := h.cached[host]; ok {
+		if time.Until(c.ExpiresAt) > refreshAhead {
+			h.mu.Unlock()
+			return c, nil
+		}
+		delete(h.cached, host)
+	}
+	h.mu.Unlock()
+
+	creds, err := h.helper.GetCredentials(ctx, ref, host)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if !creds.ExpiresAt.IsZero() {
+		h.mu.Lock()
+		h.cached[host] = creds
+		h.mu.Unlock()
+	}
+	return creds, nil
+}
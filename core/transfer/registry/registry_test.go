@@ -0,0 +1,473 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/v2/core/remotes"
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+)
+
+type recordingTransport struct {
+	http.Transport
+	closed bool
+}
+
+func (t *recordingTransport) CloseIdleConnections() {
+	t.closed = true
+}
+
+func TestRegistryClose(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewOCIRegistry(ctx, "registry.test/library/app:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := &recordingTransport{}
+	if err := r.trackClient(&http.Client{Transport: tr}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !tr.closed {
+		t.Error("expected CloseIdleConnections to be invoked")
+	}
+
+	if _, _, err := r.Resolve(ctx); !errdefs.IsUnavailable(err) {
+		t.Errorf("expected unavailable error after Close, got %v", err)
+	}
+	if _, err := r.Fetcher(ctx, "registry.test/library/app:latest"); !errdefs.IsUnavailable(err) {
+		t.Errorf("expected unavailable error after Close, got %v", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWithHeaderFunc(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewOCIRegistry(ctx, "registry.test/library/app:latest",
+		WithHeaderFunc(func(req *http.Request) {
+			req.Header.Set("X-Trace-Id", "trace-123")
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	client := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			got = req.Header.Get("X-Trace-Id")
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       http.NoBody,
+				Request:    req,
+			}, nil
+		}),
+	}
+	if err := r.trackClient(client); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://registry.test/v2/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got != "trace-123" {
+		t.Errorf("expected header func to set X-Trace-Id, got %q", got)
+	}
+	if req.Header.Get("X-Trace-Id") != "" {
+		t.Error("original request must not be mutated")
+	}
+}
+
+type countingCredHelper struct {
+	calls int
+	ttl   time.Duration
+}
+
+func (h *countingCredHelper) GetCredentials(ctx context.Context, ref, host string) (Credentials, error) {
+	h.calls++
+	c := Credentials{
+		Host:     host,
+		Username: "user",
+		Secret:   fmt.Sprintf("token-%d", h.calls),
+	}
+	if h.ttl > 0 {
+		c.ExpiresAt = time.Now().Add(h.ttl)
+	}
+	return c, nil
+}
+
+func TestProactiveTokenRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("LongLivedTokenCached", func(t *testing.T) {
+		helper := &countingCredHelper{ttl: time.Hour}
+		h := newRefreshingCredHelper(helper)
+		for i := 0; i < 2; i++ {
+			c, err := h.GetCredentials(ctx, "registry.test/app:latest", "registry.test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Secret != "token-1" {
+				t.Errorf("unexpected token %q", c.Secret)
+			}
+		}
+		if helper.calls != 1 {
+			t.Errorf("expected 1 helper call, got %d", helper.calls)
+		}
+	})
+
+	t.Run("ShortLivedTokenRefreshed", func(t *testing.T) {
+		// The token expires within the refresh-ahead window, so the
+		// second request must fetch a fresh token instead of reusing
+		// the nearly expired one.
+		helper := &countingCredHelper{ttl: time.Second}
+		h := newRefreshingCredHelper(helper)
+		if _, err := h.GetCredentials(ctx, "registry.test/app:latest", "registry.test"); err != nil {
+			t.Fatal(err)
+		}
+		c, err := h.GetCredentials(ctx, "registry.test/app:latest", "registry.test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Secret != "token-2" {
+			t.Errorf("expected refreshed token, got %q", c.Secret)
+		}
+		if helper.calls != 2 {
+			t.Errorf("expected 2 helper calls, got %d", helper.calls)
+		}
+	})
+
+	t.Run("NoExpiryNotCached", func(t *testing.T) {
+		helper := &countingCredHelper{}
+		h := newRefreshingCredHelper(helper)
+		for i := 0; i < 2; i++ {
+			if _, err := h.GetCredentials(ctx, "registry.test/app:latest", "registry.test"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if helper.calls != 2 {
+			t.Errorf("expected 2 helper calls, got %d", helper.calls)
+		}
+	})
+}
+
+func TestWithAcceptMediaTypes(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mu     sync.Mutex
+		accept string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "/manifests/") {
+			mu.Lock()
+			accept = req.Header.Get("Accept")
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	mediaTypes := []string{ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex}
+	r, err := NewOCIRegistry(ctx, host+"/library/app:latest", WithAcceptMediaTypes(mediaTypes...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// The resolve is expected to fail against the stub registry, the test
+	// only cares about the Accept header it sent.
+	if _, _, err := r.Resolve(ctx); err == nil {
+		t.Fatal("expected resolve against stub registry to fail")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := strings.Join(mediaTypes, ", "); accept != want {
+		t.Errorf("expected Accept header %q, got %q", want, accept)
+	}
+}
+
+func TestWithAcceptMediaTypesDefault(t *testing.T) {
+	headers := http.Header{"X-Custom": []string{"value"}}
+	h := resolverHeaders(headers, nil)
+	if h.Get("Accept") != "" {
+		t.Errorf("expected no Accept override by default, got %q", h.Get("Accept"))
+	}
+	h = resolverHeaders(headers, []string{"application/vnd.oci.image.manifest.v1+json"})
+	if headers.Get("Accept") != "" {
+		t.Error("caller headers must not be mutated")
+	}
+	if h.Get("X-Custom") != "value" {
+		t.Error("existing headers must be preserved")
+	}
+}
+
+func TestWithTransportTuning(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewOCIRegistry(ctx, "registry.test/library/app:latest",
+		WithTransportTuning(128, 16, 90*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tr := &http.Transport{}
+	if err := r.trackClient(&http.Client{Transport: tr}); err != nil {
+		t.Fatal(err)
+	}
+	if tr.MaxIdleConns != 128 {
+		t.Errorf("expected MaxIdleConns 128, got %d", tr.MaxIdleConns)
+	}
+	if tr.MaxIdleConnsPerHost != 16 {
+		t.Errorf("expected MaxIdleConnsPerHost 16, got %d", tr.MaxIdleConnsPerHost)
+	}
+	if tr.IdleConnTimeout != 90*time.Second {
+		t.Errorf("expected IdleConnTimeout 90s, got %s", tr.IdleConnTimeout)
+	}
+}
+
+func TestWithTransportTuningDefaults(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewOCIRegistry(ctx, "registry.test/library/app:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tr := &http.Transport{MaxIdleConns: 7, IdleConnTimeout: time.Minute}
+	if err := r.trackClient(&http.Client{Transport: tr}); err != nil {
+		t.Fatal(err)
+	}
+	// Zero tuning values must leave the transport untouched.
+	if tr.MaxIdleConns != 7 || tr.IdleConnTimeout != time.Minute {
+		t.Error("expected transport settings to be left unchanged")
+	}
+
+	if _, err := NewOCIRegistry(ctx, "registry.test/library/app:latest",
+		WithTransportTuning(-1, 0, 0)); err == nil {
+		t.Fatal("expected error for negative tuning value")
+	}
+}
+
+func TestWithConnectTimeout(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewOCIRegistry(ctx, "registry.test/library/app:latest",
+		WithConnectTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tr := &http.Transport{}
+	if err := r.trackClient(&http.Client{Transport: tr}); err != nil {
+		t.Fatal(err)
+	}
+	if tr.DialContext == nil {
+		t.Error("expected a dialer with the connect timeout to be installed")
+	}
+	if tr.TLSHandshakeTimeout != 5*time.Second {
+		t.Errorf("expected TLSHandshakeTimeout 5s, got %s", tr.TLSHandshakeTimeout)
+	}
+
+	// Zero means the transport defaults.
+	r, err = NewOCIRegistry(ctx, "registry.test/library/app:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	tr = &http.Transport{}
+	if err := r.trackClient(&http.Client{Transport: tr}); err != nil {
+		t.Fatal(err)
+	}
+	if tr.DialContext != nil || tr.TLSHandshakeTimeout != 0 {
+		t.Error("expected transport dial settings to be left unchanged")
+	}
+
+	if _, err := NewOCIRegistry(ctx, "registry.test/library/app:latest",
+		WithConnectTimeout(-time.Second)); err == nil {
+		t.Fatal("expected error for negative connect timeout")
+	}
+}
+
+// stubResolver is an in-memory remotes.Resolver serving a fixed descriptor
+// and blob, for exercising the registry wrapper without a network.
+type stubResolver struct {
+	desc ocispec.Descriptor
+}
+
+func (r *stubResolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	return ref, r.desc, nil
+}
+
+func (r *stubResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	return r, nil
+}
+
+func (r *stubResolver) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("blob")), nil
+}
+
+func (r *stubResolver) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+// captureHook records every log entry fired through the logger.
+type captureHook struct {
+	mu      sync.Mutex
+	entries []*logrus.Entry
+}
+
+func (h *captureHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *captureHook) Fire(e *logrus.Entry) error {
+	h.mu.Lock()
+	h.entries = append(h.entries, e)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *captureHook) messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	msgs := make([]string, len(h.entries))
+	for i, e := range h.entries {
+		msgs[i] = e.Message
+	}
+	return msgs
+}
+
+func TestWithLogger(t *testing.T) {
+	ctx := context.Background()
+
+	lgr := logrus.New()
+	lgr.SetOutput(io.Discard)
+	lgr.SetLevel(logrus.DebugLevel)
+	hook := &captureHook{}
+	lgr.AddHook(hook)
+
+	reg, err := NewOCIRegistry(ctx, "registry.test/app:latest", WithLogger(logrus.NewEntry(lgr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := []byte("blob")
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(blob),
+		Size:      int64(len(blob)),
+	}
+	reg.resolver = &stubResolver{desc: desc}
+
+	if _, _, err := reg.Resolve(ctx); err != nil {
+		t.Fatal(err)
+	}
+	f, err := reg.Fetcher(ctx, "registry.test/app:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := f.Fetch(ctx, desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+
+	msgs := hook.messages()
+	expected := []string{"resolving", "resolved", "fetching blob"}
+	if len(msgs) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, msgs)
+	}
+	for i, m := range expected {
+		if msgs[i] != m {
+			t.Errorf("expected event %q at position %d, got %q", m, i, msgs[i])
+		}
+	}
+
+	// The fetch event carries the digest and a running count, not payload.
+	last := hook.entries[len(hook.entries)-1]
+	if last.Data["digest"] != desc.Digest.String() {
+		t.Errorf("expected fetch event digest %s, got %v", desc.Digest, last.Data["digest"])
+	}
+	if last.Data["fetches"] != int64(1) {
+		t.Errorf("expected fetch count 1, got %v", last.Data["fetches"])
+	}
+}
+
+func TestWithLoggerNil(t *testing.T) {
+	if err := WithLogger(nil)(&registryOpts{}); err == nil {
+		t.Error("expected error for nil logger")
+	}
+}
+
+func TestWithInsecureSkipTLSVerify(t *testing.T) {
+	ctx := context.Background()
+
+	reg, err := NewOCIRegistry(ctx, "localhost:5000/app:latest", WithInsecureSkipTLSVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{}}
+	if err := reg.trackClient(client); err != nil {
+		t.Fatal(err)
+	}
+	tr := client.Transport.(*http.Transport)
+	if tr.TLSClientConfig == nil || !tr.TLSClientConfig.InsecureSkipVerify {
+		t.Error("expected TLS verification to be disabled")
+	}
+
+	// Without the opt the transport's TLS config is untouched.
+	reg, err = NewOCIRegistry(ctx, "localhost:5000/app:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: &http.Transport{}}
+	if err := reg.trackClient(client); err != nil {
+		t.Fatal(err)
+	}
+	tr = client.Transport.(*http.Transport)
+	if tr.TLSClientConfig != nil && tr.TLSClientConfig.InsecureSkipVerify {
+		t.Error("TLS verification must stay enabled by default")
+	}
+}
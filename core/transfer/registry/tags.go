@@ -0,0 +1,171 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/containerd/containerd/v2/core/remotes/docker"
+	"github.com/containerd/containerd/v2/pkg/reference"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+)
+
+// Tags lists the tags available for the registry's repository via
+// `GET /v2/<name>/tags/list`, following `Link` header pagination until the
+// registry reports no further pages. The request goes through the configured
+// hosts, transport, and credentials; hosts are tried in order and the first
+// one capable of resolving that responds is used.
+func (r *OCIRegistry) Tags(ctx context.Context) ([]string, error) {
+	if err := r.checkClosed(); err != nil {
+		return nil, err
+	}
+	refspec, err := reference.Parse(r.reference)
+	if err != nil {
+		return nil, err
+	}
+	refhost := refspec.Hostname()
+	repository := strings.TrimPrefix(refspec.Locator, refhost+"/")
+	hosts, err := r.hosts(refhost)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	for _, host := range hosts {
+		if !host.Capabilities.Has(docker.HostCapabilityResolve) {
+			continue
+		}
+		tags, err := listTags(ctx, host, repository)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			log.G(ctx).WithError(err).WithField("host", host.Host).Debug("failed to list tags")
+			continue
+		}
+		return tags, nil
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no host capable of listing tags for %s: %w", refhost, errdefs.ErrNotFound)
+	}
+	return nil, firstErr
+}
+
+// listTags pages through the tags list endpoint of a single host.
+func listTags(ctx context.Context, host docker.RegistryHost, repository string) ([]string, error) {
+	client := host.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := fmt.Sprintf("%s://%s%s/%s/tags/list", host.Scheme, host.Host, host.Path, repository)
+
+	var tags []string
+	for u != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range host.Header {
+			req.Header[k] = append(req.Header[k], v...)
+		}
+
+		resp, err := doWithAuth(ctx, client, host.Authorizer, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusNotFound {
+				return nil, fmt.Errorf("tags list for %s: %w", repository, errdefs.ErrNotFound)
+			}
+			return nil, fmt.Errorf("unexpected status %s listing tags for %s", resp.Status, repository)
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid tags list response: %w", err)
+		}
+		tags = append(tags, page.Tags...)
+
+		u, err = nextPageURL(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+// doWithAuth sends the request through the host's authorizer, retrying once
+// with refreshed credentials on an authorization challenge.
+func doWithAuth(ctx context.Context, client *http.Client, auth docker.Authorizer, req *http.Request) (*http.Response, error) {
+	if auth != nil {
+		if err := auth.Authorize(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized || auth == nil {
+		return resp, nil
+	}
+	io.Copy(io.Discard, resp.Body) //nolint:errcheck
+	resp.Body.Close()
+	if err := auth.AddResponses(ctx, []*http.Response{resp}); err != nil {
+		return resp, nil //nolint:nilerr // return the challenge response unchanged
+	}
+	retry := req.Clone(ctx)
+	if err := auth.Authorize(ctx, retry); err != nil {
+		return nil, err
+	}
+	return client.Do(retry)
+}
+
+// nextPageURL extracts the rel="next" target of the response's Link header,
+// resolved against the request URL, or empty when there are no more pages.
+func nextPageURL(resp *http.Response) (string, error) {
+	for _, link := range resp.Header.Values("Link") {
+		for _, part := range strings.Split(link, ",") {
+			fields := strings.Split(part, ";")
+			if len(fields) < 2 {
+				continue
+			}
+			target := strings.Trim(strings.TrimSpace(fields[0]), "<>")
+			for _, attr := range fields[1:] {
+				if v := strings.TrimSpace(attr); v == `rel="next"` || v == "rel=next" {
+					u, err := resp.Request.URL.Parse(target)
+					if err != nil {
+						return "", fmt.Errorf("invalid Link header %q: %w", target, err)
+					}
+					return u.String(), nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,78 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.String())
+		if r.URL.Path != "/v2/foo/bar/tags/list" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/foo/bar/tags/list?last=v2&n=2>; rel="next"`)
+			fmt.Fprint(w, `{"name":"foo/bar","tags":["v1","v2"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"name":"foo/bar","tags":["v3","latest"]}`)
+	}))
+	defer srv.Close()
+
+	host := srv.Listener.Addr().String()
+	r, err := NewOCIRegistry(ctx, host+"/foo/bar:latest", WithDefaultScheme("http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tags, err := r.Tags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"v1", "v2", "v3", "latest"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("unexpected tags %v, expected %v", tags, expected)
+	}
+	if len(requests) != 2 {
+		t.Errorf("expected 2 paginated requests, got %v", requests)
+	}
+}
+
+func TestTagsClosed(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewOCIRegistry(ctx, "localhost:5000/foo:latest", WithDefaultScheme("http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if _, err := r.Tags(ctx); err == nil {
+		t.Error("expected error listing tags on closed registry")
+	}
+}
@@ -24,6 +24,7 @@ package truncindex
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -45,6 +46,9 @@ var (
 // (multiple ids for the prefix).
 type ErrAmbiguousPrefix struct {
 	prefix string
+
+	// Matches holds all IDs sharing the ambiguous prefix, sorted.
+	Matches []string
 }
 
 func (e ErrAmbiguousPrefix) Error() string {
@@ -119,16 +123,9 @@ func (idx *TruncIndex) Get(s string) (string, error) {
 	if s == "" {
 		return "", ErrEmptyPrefix
 	}
-	var (
-		id string
-	)
+	var matches []string
 	subTreeVisitFunc := func(prefix patricia.Prefix, item patricia.Item) error {
-		if id != "" {
-			// we haven't found the ID if there are two or more IDs
-			id = ""
-			return ErrAmbiguousPrefix{prefix: s}
-		}
-		id = string(prefix)
+		matches = append(matches, string(prefix))
 		return nil
 	}
 
@@ -137,10 +134,15 @@ func (idx *TruncIndex) Get(s string) (string, error) {
 	if err := idx.trie.VisitSubtree(patricia.Prefix(s), subTreeVisitFunc); err != nil {
 		return "", err
 	}
-	if id != "" {
-		return id, nil
+	switch len(matches) {
+	case 0:
+		return "", ErrNotExist
+	case 1:
+		return matches[0], nil
+	default:
+		sort.Strings(matches)
+		return "", ErrAmbiguousPrefix{prefix: s, Matches: matches}
 	}
-	return "", ErrNotExist
 }
 
 // Iterate iterates over all stored IDs and passes each of them to the given
@@ -0,0 +1,68 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package truncindex
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetAmbiguousPrefix(t *testing.T) {
+	ids := []string{
+		"deadbeef00000000000000000000000000000000000000000000000000000001",
+		"deadbeef00000000000000000000000000000000000000000000000000000002",
+		"deadbeef00000000000000000000000000000000000000000000000000000003",
+	}
+	idx := NewTruncIndex(ids)
+
+	_, err := idx.Get("deadbeef")
+	var ambiguous ErrAmbiguousPrefix
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected ErrAmbiguousPrefix, got %v", err)
+	}
+	if len(ambiguous.Matches) != len(ids) {
+		t.Fatalf("expected %d matches, got %v", len(ids), ambiguous.Matches)
+	}
+	// Matches are sorted, and the fixture IDs are constructed in order.
+	for i, id := range ids {
+		if ambiguous.Matches[i] != id {
+			t.Errorf("expected match %d to be %s, got %s", i, id, ambiguous.Matches[i])
+		}
+	}
+	if got, want := err.Error(), "Multiple IDs found with provided prefix: deadbeef"; got != want {
+		t.Errorf("expected error string %q, got %q", want, got)
+	}
+}
+
+func TestGetUniquePrefix(t *testing.T) {
+	idx := NewTruncIndex([]string{
+		"deadbeef00000000000000000000000000000000000000000000000000000001",
+		"cafebabe00000000000000000000000000000000000000000000000000000001",
+	})
+
+	id, err := idx.Get("cafe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "cafebabe00000000000000000000000000000000000000000000000000000001" {
+		t.Errorf("unexpected id %s", id)
+	}
+
+	if _, err := idx.Get("feed"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("expected ErrNotExist, got %v", err)
+	}
+}
@@ -19,6 +19,7 @@ package progress
 import (
 	"bytes"
 	"fmt"
+	"time"
 )
 
 // TODO(stevvooe): We may want to support more interesting parameterization of
@@ -80,3 +81,33 @@ func (h Bar) Format(state fmt.State, r rune) {
 
 	state.Write(p)
 }
+
+// estimateWindow is how much further transfer at the observed rate is assumed
+// to remain when the real total is unknown.
+const estimateWindow = 10 * time.Second
+
+// EstimatedProgress produces a pseudo progress bar for operations whose total
+// size is unknown, following a diminishing-returns curve which approaches but
+// never reaches full: callers display the real completed bar only once the
+// operation finishes. The estimate assumes roughly estimateWindow of transfer
+// at the observed rate remains; without an observed rate it falls back to a
+// time-based curve over elapsed so the bar still animates. Negative inputs
+// are clamped to zero.
+func EstimatedProgress(done int64, rate BytesPerSecond, elapsed time.Duration) Bar {
+	if done < 0 {
+		done = 0
+	}
+	if rate < 0 {
+		rate = 0
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	remaining := float64(rate) * estimateWindow.Seconds()
+	if remaining <= 0 {
+		e := elapsed.Seconds()
+		return Bar(e / (e + estimateWindow.Seconds()))
+	}
+	return Bar(float64(done) / (float64(done) + remaining))
+}
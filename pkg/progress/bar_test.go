@@ -0,0 +1,64 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimatedProgress(t *testing.T) {
+	rate := BytesPerSecond(1024)
+
+	var prev Bar
+	for done := int64(0); done <= 1024*1024; done += 64 * 1024 {
+		b := EstimatedProgress(done, rate, time.Second)
+		if b < prev {
+			t.Fatalf("bar decreased from %f to %f at done=%d", float64(prev), float64(b), done)
+		}
+		if b >= 1.0 {
+			t.Fatalf("bar reached full (%f) at done=%d", float64(b), done)
+		}
+		prev = b
+	}
+	if prev == 0 {
+		t.Error("expected bar to advance as done grows")
+	}
+}
+
+func TestEstimatedProgressNoRate(t *testing.T) {
+	var prev Bar
+	for elapsed := time.Duration(0); elapsed <= time.Minute; elapsed += 5 * time.Second {
+		b := EstimatedProgress(0, 0, elapsed)
+		if b < prev {
+			t.Fatalf("bar decreased from %f to %f at elapsed=%s", float64(prev), float64(b), elapsed)
+		}
+		if b >= 1.0 {
+			t.Fatalf("bar reached full (%f) at elapsed=%s", float64(b), elapsed)
+		}
+		prev = b
+	}
+	if prev == 0 {
+		t.Error("expected time-based bar to advance as elapsed grows")
+	}
+}
+
+func TestEstimatedProgressClampsInputs(t *testing.T) {
+	if b := EstimatedProgress(-1, -1, -time.Second); b != 0 {
+		t.Errorf("expected zero bar for negative inputs, got %f", float64(b))
+	}
+}
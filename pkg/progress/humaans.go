@@ -43,3 +43,55 @@ func NewBytesPerSecond(n int64, duration time.Duration) BytesPerSecond {
 func (bps BytesPerSecond) String() string {
 	return fmt.Sprintf("%v/s", Bytes(bps))
 }
+
+// Duration renders a time.Duration in a compact, stable form matching the
+// style of Bytes, always using the shortest unit pair that fits, e.g.
+// "450ms", "1.5s", "2m3s", "1h2m".
+type Duration time.Duration
+
+// String returns the string representation of the duration
+func (d Duration) String() string {
+	t := time.Duration(d)
+	switch {
+	case t < time.Second:
+		return fmt.Sprintf("%dms", t.Milliseconds())
+	case t < time.Minute:
+		return fmt.Sprintf("%.1fs", t.Seconds())
+	case t < time.Hour:
+		m := int(t.Minutes())
+		s := int(t.Seconds()) - m*60
+		return fmt.Sprintf("%dm%ds", m, s)
+	default:
+		h := int(t.Hours())
+		m := int(t.Minutes()) - h*60
+		return fmt.Sprintf("%dh%dm", h, m)
+	}
+}
+
+// Summary describes a completed transfer for a one-line report.
+type Summary struct {
+	Items    int
+	Bytes    Bytes
+	Duration Duration
+	Rate     BytesPerSecond
+}
+
+// String returns the formatted summary line.
+func (s Summary) String() string {
+	return FormatSummary(s.Items, s.Bytes, s.Duration, s.Rate)
+}
+
+// FormatSummary composes a consistent one-line transfer summary such as
+// "12 items, 340.5 MiB in 1m3s (5.4 MiB/s)". The rate is rendered as "--"
+// when the duration is zero, since no meaningful rate exists.
+func FormatSummary(items int, bytes Bytes, dur Duration, rate BytesPerSecond) string {
+	noun := "items"
+	if items == 1 {
+		noun = "item"
+	}
+	rateStr := "--"
+	if time.Duration(dur) > 0 {
+		rateStr = rate.String()
+	}
+	return fmt.Sprintf("%d %s, %v in %v (%s)", items, noun, bytes, dur, rateStr)
+}
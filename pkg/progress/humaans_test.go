@@ -0,0 +1,95 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package progress
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDurationString(t *testing.T) {
+	for _, tc := range []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0ms"},
+		{450 * time.Millisecond, "450ms"},
+		{1500 * time.Millisecond, "1.5s"},
+		{59 * time.Second, "59.0s"},
+		{2*time.Minute + 3*time.Second, "2m3s"},
+		{59*time.Minute + 59*time.Second, "59m59s"},
+		{time.Hour + 2*time.Minute, "1h2m"},
+		{25*time.Hour + 30*time.Minute, "25h30m"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			if s := Duration(tc.duration).String(); s != tc.expected {
+				t.Errorf("unexpected format %q, expected %q", s, tc.expected)
+			}
+			// The type must remain %s-compatible for progress lines.
+			if s := fmt.Sprintf("%s", Duration(tc.duration)); s != tc.expected {
+				t.Errorf("unexpected %%s format %q, expected %q", s, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFormatSummary(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		summary  Summary
+		expected string
+	}{
+		{
+			name: "plural",
+			summary: Summary{
+				Items:    12,
+				Bytes:    Bytes(357039718),
+				Duration: Duration(time.Minute + 3*time.Second),
+				Rate:     NewBytesPerSecond(357039718, time.Minute+3*time.Second),
+			},
+			expected: "12 items, 340.5 MiB in 1m3s (5.4 MiB/s)",
+		},
+		{
+			name: "singular",
+			summary: Summary{
+				Items:    1,
+				Bytes:    Bytes(1024),
+				Duration: Duration(2 * time.Second),
+				Rate:     NewBytesPerSecond(1024, 2*time.Second),
+			},
+			expected: "1 item, 1.0 KiB in 2.0s (512.0 B/s)",
+		},
+		{
+			name: "zero duration",
+			summary: Summary{
+				Items: 3,
+				Bytes: Bytes(2048),
+			},
+			expected: "3 items, 2.0 KiB in 0ms (--)",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if s := tc.summary.String(); s != tc.expected {
+				t.Errorf("unexpected summary %q, expected %q", s, tc.expected)
+			}
+			if s := FormatSummary(tc.summary.Items, tc.summary.Bytes, tc.summary.Duration, tc.summary.Rate); s != tc.expected {
+				t.Errorf("unexpected summary %q, expected %q", s, tc.expected)
+			}
+		})
+	}
+}
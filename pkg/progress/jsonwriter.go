@@ -0,0 +1,76 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// JSONEvent is a single machine-readable progress event. Bytes fields are
+// emitted as raw numbers rather than the human-formatted strings used by the
+// terminal writer.
+type JSONEvent struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Current   Bytes     `json:"current"`
+	Total     Bytes     `json:"total,omitempty"`
+	Complete  bool      `json:"complete,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// JSONWriter emits progress events as newline-delimited JSON for consumption
+// by structured frontends, as an alternative to rendered terminal frames.
+type JSONWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONWriter returns a writer emitting newline-delimited JSON events to w.
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Status emits a progress update for the object identified by id.
+func (jw *JSONWriter) Status(id, name string, current, total Bytes) error {
+	return jw.emit(JSONEvent{
+		ID:      id,
+		Name:    name,
+		Current: current,
+		Total:   total,
+	})
+}
+
+// Complete emits a completion event for the object identified by id.
+func (jw *JSONWriter) Complete(id, name string) error {
+	return jw.emit(JSONEvent{
+		ID:       id,
+		Name:     name,
+		Complete: true,
+	})
+}
+
+func (jw *JSONWriter) emit(ev JSONEvent) error {
+	ev.Timestamp = time.Now().UTC()
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+	return jw.enc.Encode(ev)
+}
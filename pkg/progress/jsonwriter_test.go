@@ -0,0 +1,74 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	jw := NewJSONWriter(&buf)
+
+	if err := jw.Status("layer-1", "downloading", 512, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if err := jw.Status("layer-1", "downloading", 2048, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if err := jw.Complete("layer-1", "done"); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 NDJSON lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var events []JSONEvent
+	for i, line := range lines {
+		var ev JSONEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v", i, err)
+		}
+		if ev.ID != "layer-1" {
+			t.Errorf("line %d: unexpected id %q", i, ev.ID)
+		}
+		if ev.Timestamp.IsZero() {
+			t.Errorf("line %d: timestamp not set", i)
+		}
+		events = append(events, ev)
+	}
+
+	if events[0].Current != 512 || events[0].Total != 2048 {
+		t.Errorf("unexpected first event %+v", events[0])
+	}
+	if events[1].Current != 2048 {
+		t.Errorf("unexpected second event %+v", events[1])
+	}
+	if !events[2].Complete {
+		t.Error("expected final event to be a completion")
+	}
+
+	// Byte counts must be raw numbers, not formatted strings.
+	if strings.Contains(lines[0], "KiB") || strings.Contains(lines[0], `"current":"`) {
+		t.Errorf("expected raw numeric fields, got %q", lines[0])
+	}
+}
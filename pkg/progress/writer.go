@@ -18,11 +18,15 @@ package progress
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/containerd/console"
 )
@@ -36,9 +40,18 @@ var (
 // implementing progress displays, such as those implemented in docker and
 // git.
 type Writer struct {
-	buf   bytes.Buffer
-	w     io.Writer
-	lines int
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	w         io.Writer
+	log       io.Writer
+	frame     string
+	pending   string
+	lines     int
+	maxLines  int
+	autoFlush bool
+
+	minFlushInterval time.Duration
+	lastFlush        time.Time
 }
 
 // NewWriter returns a writer
@@ -48,27 +61,148 @@ func NewWriter(w io.Writer) *Writer {
 	}
 }
 
+// NewTeeWriter returns a writer which renders the animated progress display
+// to console while also writing a plain-text copy of each flushed frame to
+// log. The copy sent to log is stripped of ANSI escape sequences and contains
+// no cursor movement, making it suitable for capture in build logs.
+func NewTeeWriter(console io.Writer, log io.Writer) *Writer {
+	return &Writer{
+		w:   console,
+		log: log,
+	}
+}
+
 // Write the provided bytes
 func (w *Writer) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.buf.Write(p)
 }
 
+// SetMaxLines bounds the render region to at most n status lines plus a
+// summary line. When a frame has more lines than the cap, the overflow is
+// collapsed into a trailing "... and M more" line, keeping the cursor
+// movement on the next flush within n+1 lines and the scrollback intact
+// regardless of how many logical rows exist. A non-positive n removes the
+// bound. The plain-text copy written by a tee writer is not truncated.
+func (w *Writer) SetMaxLines(n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.maxLines = n
+}
+
+// SetMinFlushInterval coalesces Flush calls arriving faster than d: the
+// buffered frame is retained but the terminal is redrawn at most once per
+// interval, with the latest frame rendered by the first Flush after the
+// interval elapses. This keeps per-chunk progress updates from thrashing
+// the terminal. A non-positive d restores flush-per-call behavior.
+func (w *Writer) SetMinFlushInterval(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.minFlushInterval = d
+}
+
 // Flush should be called when refreshing the current display.
 func (w *Writer) Flush() error {
-	if w.buf.Len() == 0 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	src := w.buf.String()
+	if src == "" {
+		// A coalesced frame may still be waiting for its redraw.
+		src = w.pending
+	}
+	if src == "" {
 		return nil
 	}
+	if w.minFlushInterval > 0 && time.Since(w.lastFlush) < w.minFlushInterval {
+		// Too soon since the last redraw: keep the latest frame and
+		// render it on the first flush after the interval.
+		w.pending = src
+		w.buf.Reset()
+		return nil
+	}
+	w.pending = ""
+
+	frame := src
+	if w.maxLines > 0 {
+		frame = limitFrame(frame, w.maxLines)
+	}
 
 	if err := w.clearLines(); err != nil {
 		return err
 	}
-	w.lines = countLines(w.buf.String())
+	w.lines = countLines(frame)
 
-	if _, err := w.w.Write(w.buf.Bytes()); err != nil {
+	if _, err := io.WriteString(w.w, frame); err != nil {
 		return err
 	}
 
+	if w.log != nil {
+		if _, err := io.WriteString(w.log, stripFrame(src)); err != nil {
+			return err
+		}
+	}
+
+	w.frame = frame
 	w.buf.Reset()
+	w.lastFlush = time.Now()
+	return nil
+}
+
+// Snapshot returns the status lines of the current frame as plain text,
+// stripped of ANSI escape sequences and cursor controls. Pending writes not
+// yet flushed take precedence over the last flushed frame. This allows
+// non-interactive callers to log progress on an interval instead of
+// rendering animated output.
+func (w *Writer) Snapshot() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	src := w.buf.String()
+	if src == "" {
+		src = w.pending
+	}
+	if src == "" {
+		src = w.frame
+	}
+	if src == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(stripFrame(src), "\n"), "\n")
+}
+
+// StartAutoFlush spawns a goroutine which flushes the writer every interval
+// until ctx is canceled, with a final flush when it stops, so callers do not
+// need to drive Flush themselves. Flush errors are ignored; the next tick
+// retries. Calling it while a previous auto-flush is still running returns
+// an error.
+func (w *Writer) StartAutoFlush(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("flush interval must be positive: %s", interval)
+	}
+	w.mu.Lock()
+	if w.autoFlush {
+		w.mu.Unlock()
+		return errors.New("auto-flush already started")
+	}
+	w.autoFlush = true
+	w.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.Flush()
+			case <-ctx.Done():
+				w.Flush()
+				w.mu.Lock()
+				w.autoFlush = false
+				w.mu.Unlock()
+				return
+			}
+		}
+	}()
 	return nil
 }
 
@@ -113,3 +247,24 @@ func countLines(output string) int {
 func stripLine(line string) string {
 	return string(regexCleanLine.ReplaceAll([]byte(line), []byte{}))
 }
+
+// limitFrame truncates a rendered frame to at most max lines, replacing the
+// overflow with a single summary line.
+func limitFrame(frame string, max int) string {
+	lines := strings.Split(strings.TrimSuffix(frame, "\n"), "\n")
+	if len(lines) <= max {
+		return frame
+	}
+	hidden := len(lines) - max
+	return strings.Join(append(lines[:max], fmt.Sprintf("... and %d more", hidden)), "\n") + "\n"
+}
+
+// stripFrame removes ANSI escape sequences from each line of a rendered
+// frame, returning a newline-terminated plain-text copy.
+func stripFrame(frame string) string {
+	lines := strings.Split(strings.TrimSuffix(frame, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = stripLine(line)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
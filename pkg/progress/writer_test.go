@@ -0,0 +1,269 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package progress
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTeeWriterStripsEscapes(t *testing.T) {
+	var console, log bytes.Buffer
+	w := NewTeeWriter(&console, &log)
+
+	fmt.Fprintf(w, "%slayer-1:%s done\n", green, reset)
+	fmt.Fprintf(w, "layer-2: downloading\n")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(console.String(), string(green)) {
+		t.Error("console output missing color escape")
+	}
+
+	expected := "layer-1: done\nlayer-2: downloading\n"
+	if log.String() != expected {
+		t.Errorf("unexpected log output %q, expected %q", log.String(), expected)
+	}
+	if strings.Contains(log.String(), "\x1b") {
+		t.Error("log output contains escape sequences")
+	}
+}
+
+func TestWriterSnapshot(t *testing.T) {
+	var console bytes.Buffer
+	w := NewWriter(&console)
+
+	if lines := w.Snapshot(); lines != nil {
+		t.Errorf("expected nil snapshot for empty writer, got %v", lines)
+	}
+
+	fmt.Fprintf(w, "%slayer-1:%s done\n", green, reset)
+	fmt.Fprintf(w, "layer-2: downloading\n")
+
+	expected := []string{"layer-1: done", "layer-2: downloading"}
+	check := func(stage string) {
+		lines := w.Snapshot()
+		if len(lines) != len(expected) {
+			t.Fatalf("%s: unexpected snapshot %v", stage, lines)
+		}
+		for i := range lines {
+			if lines[i] != expected[i] {
+				t.Errorf("%s: unexpected line %q, expected %q", stage, lines[i], expected[i])
+			}
+			if strings.Contains(lines[i], "\x1b") {
+				t.Errorf("%s: snapshot line %q contains escape sequences", stage, lines[i])
+			}
+		}
+	}
+
+	check("pending")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	check("flushed")
+}
+
+// lockedBuffer is a goroutine-safe sink for auto-flushed frames.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitForOutput(t *testing.T, buf *lockedBuffer, substr string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if strings.Contains(buf.String(), substr) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %q in output %q", substr, buf.String())
+}
+
+func TestStartAutoFlush(t *testing.T) {
+	var buf lockedBuffer
+	w := NewWriter(&buf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := w.StartAutoFlush(ctx, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.StartAutoFlush(ctx, time.Millisecond); err == nil {
+		t.Error("expected error starting auto-flush twice")
+	}
+
+	fmt.Fprintf(w, "layer-1: downloading\n")
+	waitForOutput(t, &buf, "layer-1: downloading")
+
+	// A final flush must pick up writes racing with cancellation.
+	fmt.Fprintf(w, "layer-1: done\n")
+	cancel()
+	waitForOutput(t, &buf, "layer-1: done")
+
+	// Once stopped, auto-flush can be started again.
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if err := w.StartAutoFlush(ctx2, time.Millisecond); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for previous auto-flush to stop")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStartAutoFlushInvalidInterval(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{})
+	if err := w.StartAutoFlush(context.Background(), 0); err == nil {
+		t.Fatal("expected error for non-positive interval")
+	}
+}
+
+func TestSetMaxLines(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	w.SetMaxLines(4)
+
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(w, "row-%d\n", i)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 4 rows plus summary, got %d lines: %q", len(lines), lines)
+	}
+	for i := 0; i < 4; i++ {
+		if expected := fmt.Sprintf("row-%d", i); lines[i] != expected {
+			t.Errorf("expected %q at line %d, got %q", expected, i, lines[i])
+		}
+	}
+	if lines[4] != "... and 6 more" {
+		t.Errorf("unexpected summary line %q", lines[4])
+	}
+
+	// The retained frame matches what was rendered, so snapshots and the
+	// next clear stay within the bounded region.
+	if snap := w.Snapshot(); len(snap) != 5 || snap[4] != "... and 6 more" {
+		t.Errorf("unexpected snapshot %q", snap)
+	}
+
+	// Under the cap the frame passes through untouched.
+	out.Reset()
+	fmt.Fprintf(w, "row-0\nrow-1\n")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if s := out.String(); s != "row-0\nrow-1\n" {
+		t.Errorf("unexpected output %q", s)
+	}
+}
+
+func TestSetMaxLinesDoesNotTruncateLog(t *testing.T) {
+	var out, logged bytes.Buffer
+	w := NewTeeWriter(&out, &logged)
+	w.SetMaxLines(1)
+
+	fmt.Fprintf(w, "row-0\nrow-1\nrow-2\n")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := logged.String(); s != "row-0\nrow-1\nrow-2\n" {
+		t.Errorf("log copy truncated: %q", s)
+	}
+	if s := out.String(); s != "row-0\n... and 2 more\n" {
+		t.Errorf("unexpected rendered output %q", s)
+	}
+}
+
+// flushCountingWriter counts frame writes (ignoring clear sequences, which
+// are not emitted without a console).
+type flushCountingWriter struct {
+	writes int
+	last   string
+}
+
+func (f *flushCountingWriter) Write(p []byte) (int, error) {
+	f.writes++
+	f.last = string(p)
+	return len(p), nil
+}
+
+func TestSetMinFlushInterval(t *testing.T) {
+	var out flushCountingWriter
+	w := NewWriter(&out)
+	w.SetMinFlushInterval(time.Hour)
+
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(w, "frame-%d\n", i)
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the first flush redraws; the rest coalesce.
+	if out.writes != 1 {
+		t.Errorf("expected 1 redraw, got %d", out.writes)
+	}
+	if out.last != "frame-0\n" {
+		t.Errorf("unexpected frame %q", out.last)
+	}
+
+	// The latest coalesced frame is still observable.
+	if lines := w.Snapshot(); len(lines) != 1 || lines[0] != "frame-9" {
+		t.Errorf("unexpected snapshot %v", lines)
+	}
+
+	// After the interval elapses the next flush renders the latest frame.
+	w.SetMinFlushInterval(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out.writes != 2 {
+		t.Errorf("expected final redraw, got %d writes", out.writes)
+	}
+	if out.last != "frame-9\n" {
+		t.Errorf("unexpected final frame %q", out.last)
+	}
+}
@@ -0,0 +1,44 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package rootfs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/containerd/containerd/v2/core/snapshots"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/identity"
+)
+
+// VerifyChainIDs checks that every prefix of the given diff ID chain has a
+// committed snapshot, statting each chain ID from the bottom layer up. It
+// returns an error naming the first missing chain link, catching partial
+// unpacks where an intermediate snapshot was removed.
+func VerifyChainIDs(ctx context.Context, diffIDs []digest.Digest, sn snapshots.Snapshotter) error {
+	for i := range diffIDs {
+		chainID := identity.ChainID(diffIDs[:i+1])
+		if _, err := sn.Stat(ctx, chainID.String()); err != nil {
+			if errdefs.IsNotFound(err) {
+				return fmt.Errorf("chain link %d of %d (%s) missing: %w", i+1, len(diffIDs), chainID, err)
+			}
+			return fmt.Errorf("failed to stat snapshot %s: %w", chainID, err)
+		}
+	}
+	return nil
+}
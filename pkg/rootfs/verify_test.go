@@ -0,0 +1,71 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package rootfs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/snapshots"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/identity"
+)
+
+// statSnapshotter stats against a fixed set of committed snapshot keys.
+type statSnapshotter struct {
+	snapshots.Snapshotter
+	committed map[string]struct{}
+}
+
+func (s *statSnapshotter) Stat(ctx context.Context, key string) (snapshots.Info, error) {
+	if _, ok := s.committed[key]; !ok {
+		return snapshots.Info{}, fmt.Errorf("snapshot %s: %w", key, errdefs.ErrNotFound)
+	}
+	return snapshots.Info{Name: key, Kind: snapshots.KindCommitted}, nil
+}
+
+func TestVerifyChainIDs(t *testing.T) {
+	ctx := context.Background()
+	diffIDs := []digest.Digest{
+		digest.FromString("layer-0"),
+		digest.FromString("layer-1"),
+		digest.FromString("layer-2"),
+	}
+
+	sn := &statSnapshotter{committed: map[string]struct{}{}}
+	for i := range diffIDs {
+		sn.committed[identity.ChainID(diffIDs[:i+1]).String()] = struct{}{}
+	}
+
+	if err := VerifyChainIDs(ctx, diffIDs, sn); err != nil {
+		t.Errorf("expected fully present chain to verify, got %v", err)
+	}
+
+	// Removing an intermediate link must name it in the error.
+	missing := identity.ChainID(diffIDs[:2]).String()
+	delete(sn.committed, missing)
+	err := VerifyChainIDs(ctx, diffIDs, sn)
+	if !errdefs.IsNotFound(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), missing) || !strings.Contains(err.Error(), "chain link 2 of 3") {
+		t.Errorf("expected error naming the missing link, got %v", err)
+	}
+}
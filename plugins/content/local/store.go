@@ -524,6 +524,13 @@ func (s *store) writer(ctx context.Context, ref string, total int64, expected di
 		startedAt time.Time
 		updatedAt time.Time
 	)
+	if expected != "" {
+		// Digest with the algorithm the caller expects to commit under, so
+		// non-canonical algorithms such as sha512 can be ingested.
+		if alg := expected.Algorithm(); alg.Available() {
+			digester = alg.Digester()
+		}
+	}
 
 	foundValidIngest := false
 	// ensure that the ingest path has been created.
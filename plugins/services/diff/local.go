@@ -90,6 +90,28 @@ type local struct {
 
 var _ diffapi.DiffClient = &local{}
 
+// validateMounts performs pre-flight validation on mounts before they are
+// handed to a differ, so that malformed requests surface as InvalidArgument
+// rather than an opaque backend error. The name identifies the mount set
+// (e.g. "left", "right") in the returned error.
+func validateMounts(name string, mounts []mount.Mount) error {
+	for i, m := range mounts {
+		if m.Type == "" {
+			return fmt.Errorf("%s mount %d: empty mount type: %w", name, i, errdefs.ErrInvalidArgument)
+		}
+		switch m.Type {
+		case "overlay", "tmpfs":
+			// source is not a host path for these types, multi-source
+			// mounts such as overlay carry sources in the options
+		default:
+			if m.Source == "" {
+				return fmt.Errorf("%s mount %d: empty mount source: %w", name, i, errdefs.ErrInvalidArgument)
+			}
+		}
+	}
+	return nil
+}
+
 func (l *local) Apply(ctx context.Context, er *diffapi.ApplyRequest, _ ...grpc.CallOption) (*diffapi.ApplyResponse, error) {
 	var (
 		ocidesc ocispec.Descriptor
@@ -98,6 +120,10 @@ func (l *local) Apply(ctx context.Context, er *diffapi.ApplyRequest, _ ...grpc.C
 		mounts  = mount.FromProto(er.Mounts)
 	)
 
+	if err := validateMounts("apply", mounts); err != nil {
+		return nil, errdefs.ToGRPC(err)
+	}
+
 	var opts []diff.ApplyOpt
 	if er.Payloads != nil {
 		payloads := make(map[string]typeurl.Any)
@@ -133,6 +159,13 @@ func (l *local) Diff(ctx context.Context, dr *diffapi.DiffRequest, _ ...grpc.Cal
 		bMounts = mount.FromProto(dr.Right)
 	)
 
+	if err := validateMounts("left", aMounts); err != nil {
+		return nil, errdefs.ToGRPC(err)
+	}
+	if err := validateMounts("right", bMounts); err != nil {
+		return nil, errdefs.ToGRPC(err)
+	}
+
 	var opts []diff.Opt
 	if dr.MediaType != "" {
 		opts = append(opts, diff.WithMediaType(dr.MediaType))
@@ -0,0 +1,78 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/mount"
+	"github.com/containerd/errdefs"
+)
+
+func TestValidateMounts(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		mounts    []mount.Mount
+		expectErr bool
+	}{
+		{
+			name: "MissingType",
+			mounts: []mount.Mount{
+				{Source: "/tmp/snapshot"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "MissingSource",
+			mounts: []mount.Mount{
+				{Type: "bind"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "ValidBind",
+			mounts: []mount.Mount{
+				{Type: "bind", Source: "/tmp/snapshot", Options: []string{"rbind", "ro"}},
+			},
+		},
+		{
+			name: "ValidOverlayMultiSource",
+			mounts: []mount.Mount{
+				{
+					Type: "overlay",
+					Options: []string{
+						"index=off",
+						"lowerdir=/var/lib/snapshots/2/fs:/var/lib/snapshots/1/fs",
+						"upperdir=/var/lib/snapshots/3/fs",
+						"workdir=/var/lib/snapshots/3/work",
+					},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateMounts("test", tc.mounts)
+			if tc.expectErr {
+				if !errdefs.IsInvalidArgument(err) {
+					t.Fatalf("expected invalid argument error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}